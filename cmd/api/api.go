@@ -12,7 +12,10 @@ import (
 	"github.com/sangtandoan/social/internal/config"
 	"github.com/sangtandoan/social/internal/middleware"
 	"github.com/sangtandoan/social/internal/service"
+	"github.com/sangtandoan/social/internal/service/abac"
+	"github.com/sangtandoan/social/internal/service/auth"
 	"github.com/sangtandoan/social/internal/service/cache"
+	"github.com/sangtandoan/social/internal/service/oauth"
 	"github.com/sangtandoan/social/internal/store"
 	"github.com/sangtandoan/social/internal/utils"
 
@@ -20,16 +23,25 @@ import (
 )
 
 type application struct {
-	config *config.Config
-	store  *store.Store
-	mailer service.Mailer
-	cache  *cache.CacheService
-	srv    *http.Server
+	config     *config.Config
+	store      *store.Store
+	mailer     service.Mailer
+	cache      cache.Cache
+	tokenMaker *auth.TokenMaker
+	// Keyed by the name used in /users/oauth/:provider routes
+	oauthProviders map[string]oauth.Provider
+	fakeIssuer     *oauth.FakeIssuer
+	abac           *abac.Engine
+	srv            *http.Server
 }
 
 func (a *application) mount() http.Handler {
 	r := gin.Default()
 
+	if a.fakeIssuer != nil {
+		r.Any("/oauth/fake/*path", gin.WrapH(a.fakeIssuer))
+	}
+
 	api := r.Group("/api")
 	api.Use(middleware.GlobalErrorHandler())
 	{
@@ -37,7 +49,13 @@ func (a *application) mount() http.Handler {
 		{
 			a.setupPostRoutes(v1)
 			a.setupUserRoutes(v1)
-			v1.GET("/feeds", a.getUserFeedHandler)
+			a.setupPolicyRoutes(v1)
+			v1.GET(
+				"/feeds",
+				a.authenticate(),
+				middleware.RequireScope(auth.ScopeFeedRead),
+				a.getUserFeedHandler,
+			)
 		}
 	}
 
@@ -49,16 +67,197 @@ func (a *application) setupUserRoutes(group *gin.RouterGroup) {
 
 	users.POST("", a.createUserHandler)
 	users.PATCH("/activate", a.activateUserHandler)
+	users.POST(
+		"/activate/resend",
+		middleware.RateLimit(a.cache, "activate-resend", 3, 15*time.Minute),
+		a.resendActivationHandler,
+	)
 	users.POST("/login", a.loginHandler)
+	users.POST(
+		"/login/mfa",
+		middleware.RateLimit(a.cache, "login-mfa", 10, 5*time.Minute),
+		a.mfaLoginHandler,
+	)
+	users.POST("/token/refresh", a.refreshTokenHandler)
+	users.GET("/oauth/:provider/start", a.oauthStartHandler)
+	users.GET("/oauth/:provider/callback", a.oauthCallbackHandler)
+	users.POST("/password/forgot", a.forgotPasswordHandler)
+	users.POST("/password/reset", a.resetPasswordHandler)
+
+	me := users.Group("/me", a.authenticate(), middleware.RequireSession())
+	me.GET("/sessions", a.getSessionsHandler)
+	me.DELETE("/sessions", a.revokeAllSessionsHandler)
+	me.DELETE("/sessions/:id", a.revokeSessionHandler)
+	me.POST("/mfa/enroll", a.enrollMFAHandler)
+	me.POST("/mfa/confirm", a.confirmMFAHandler)
+	me.POST("/mfa/disable", a.disableMFAHandler)
+	me.GET("/api-keys", a.getAPIKeysHandler)
+	me.POST("/api-keys", a.createAPIKeyHandler)
+	me.DELETE("/api-keys/:id", a.deleteAPIKeyHandler)
+	me.GET("/permissions", a.getPermissionsHandler)
+	me.PUT("/privacy", a.setPrivacyHandler)
+	me.GET("/follow-requests", a.getFollowRequestsHandler)
+	me.POST("/follow-requests/:id/approve", a.approveFollowRequestHandler)
+	me.DELETE("/follow-requests/:id", a.rejectFollowRequestHandler)
+
+	// API keys read on behalf of their owner, so they need the read scope like the
+	// post routes do
+	profile := users.Group(
+		"/:id",
+		a.optionalAuthenticate(),
+		middleware.RequireScope(auth.ScopePostsRead),
+	)
+	profile.GET("", a.getUserProfileHandler)
+	profile.GET("/followers", a.getFollowersHandler)
+	profile.GET("/following", a.getFollowingHandler)
+
+	follow := users.Group("/:id/follow", a.authenticate(), middleware.RequireSession())
+	follow.PUT("", a.followUserHandler)
+	follow.DELETE("", a.unfollowUserHandler)
+	follow.DELETE("/request", a.cancelFollowRequestHandler)
+
+	moderation := users.Group("/:id", a.authenticate(), middleware.RequireSession())
+	moderation.PUT(
+		"/role",
+		middleware.RequirePermission(a.store.Roles, store.PermRoleAssign),
+		a.assignRoleHandler,
+	)
+	moderation.POST(
+		"/suspend",
+		middleware.RequirePermission(a.store.Roles, store.PermUserSuspend),
+		a.suspendUserHandler,
+	)
+	moderation.DELETE(
+		"/suspend",
+		middleware.RequirePermission(a.store.Roles, store.PermUserSuspend),
+		a.unsuspendUserHandler,
+	)
 }
 
 func (a *application) setupPostRoutes(group *gin.RouterGroup) {
 	posts := group.Group("/posts")
 
-	posts.POST("", utils.MakeHandlerFunc(a.createPostHandler))
-	posts.PATCH("/:id", utils.MakeHandlerFunc(a.updatePostHandler))
-	posts.GET("/:id", utils.MakeHandlerFunc(a.getPostHandler))
-	posts.GET("", utils.MakeHandlerFunc(a.getPostsHandler))
+	posts.POST(
+		"",
+		a.authenticate(),
+		middleware.RequireScope(auth.ScopePostsWrite),
+		utils.MakeHandlerFunc(a.createPostHandler),
+	)
+	posts.PATCH(
+		"/:id",
+		a.authenticate(),
+		middleware.RequireScope(auth.ScopePostsWrite),
+		utils.MakeHandlerFunc(a.updatePostHandler),
+	)
+	posts.DELETE(
+		"/:id",
+		a.authenticate(),
+		middleware.RequireScope(auth.ScopePostsWrite),
+		utils.MakeHandlerFunc(a.deletePostHandler),
+	)
+	posts.POST(
+		"/:id/restore",
+		a.authenticate(),
+		middleware.RequireScope(auth.ScopePostsWrite),
+		utils.MakeHandlerFunc(a.restorePostHandler),
+	)
+	posts.GET(
+		"/:id",
+		a.optionalAuthenticate(),
+		middleware.RequireScope(auth.ScopePostsRead),
+		utils.MakeHandlerFunc(a.getPostHandler),
+	)
+	posts.GET(
+		"/:id/revisions",
+		a.authenticate(),
+		middleware.RequireScope(auth.ScopePostsRead),
+		utils.MakeHandlerFunc(a.getPostRevisionsHandler),
+	)
+	posts.GET(
+		"/:id/revisions/:rev",
+		a.authenticate(),
+		middleware.RequireScope(auth.ScopePostsRead),
+		utils.MakeHandlerFunc(a.getPostRevisionHandler),
+	)
+	posts.GET(
+		"/:id/diff",
+		a.authenticate(),
+		middleware.RequireScope(auth.ScopePostsRead),
+		utils.MakeHandlerFunc(a.getPostDiffHandler),
+	)
+	posts.GET(
+		"",
+		a.optionalAuthenticate(),
+		middleware.RequireScope(auth.ScopePostsRead),
+		utils.MakeHandlerFunc(a.getPostsHandler),
+	)
+
+	posts.PUT(
+		"/:id/reactions",
+		a.authenticate(),
+		middleware.RequireScope(auth.ScopePostsWrite),
+		utils.MakeHandlerFunc(a.togglePostReactionHandler),
+	)
+
+	comments := posts.Group("/:id/comments")
+	comments.GET(
+		"",
+		a.optionalAuthenticate(),
+		middleware.RequireScope(auth.ScopePostsRead),
+		utils.MakeHandlerFunc(a.getCommentsHandler),
+	)
+	comments.POST(
+		"",
+		a.authenticate(),
+		middleware.RequireScope(auth.ScopePostsWrite),
+		utils.MakeHandlerFunc(a.createCommentHandler),
+	)
+	comments.GET(
+		"/:commentID/replies",
+		a.optionalAuthenticate(),
+		middleware.RequireScope(auth.ScopePostsRead),
+		utils.MakeHandlerFunc(a.getCommentRepliesHandler),
+	)
+	comments.PUT(
+		"/:commentID/reactions",
+		a.authenticate(),
+		middleware.RequireScope(auth.ScopePostsWrite),
+		utils.MakeHandlerFunc(a.toggleCommentReactionHandler),
+	)
+	comments.PATCH(
+		"/:commentID",
+		a.authenticate(),
+		middleware.RequireScope(auth.ScopePostsWrite),
+		utils.MakeHandlerFunc(a.updateCommentHandler),
+	)
+	comments.DELETE(
+		"/:commentID",
+		a.authenticate(),
+		middleware.RequireScope(auth.ScopePostsWrite),
+		utils.MakeHandlerFunc(a.deleteCommentHandler),
+	)
+}
+
+func (a *application) setupPolicyRoutes(group *gin.RouterGroup) {
+	policies := group.Group(
+		"/policies",
+		a.authenticate(),
+		middleware.RequireSession(),
+		middleware.RequirePermission(a.store.Roles, store.PermPolicyManage),
+	)
+
+	policies.GET("", a.getPoliciesHandler)
+	policies.POST("", a.createPolicyHandler)
+	policies.PUT("/:id", a.updatePolicyHandler)
+	policies.DELETE("/:id", a.deletePolicyHandler)
+}
+
+func (a *application) authenticate() gin.HandlerFunc {
+	return middleware.Authenticate(a.tokenMaker, a.store.APIKeys)
+}
+
+func (a *application) optionalAuthenticate() gin.HandlerFunc {
+	return middleware.OptionalAuthenticate(a.tokenMaker, a.store.APIKeys)
 }
 
 func (a *application) run(mux http.Handler) error {
@@ -89,6 +288,13 @@ func (a *application) run(mux http.Handler) error {
 		shutdown <- srv.Shutdown(ctx)
 	}()
 
+	jobCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+
+	go a.purgeDeletedPosts(jobCtx)
+	go a.publishScheduledPosts(jobCtx)
+	go a.reconcileReactions(jobCtx)
+
 	err := srv.ListenAndServe()
 	if !errors.Is(err, http.ErrServerClosed) {
 		utils.Log.Infof("Server error: $v", err)
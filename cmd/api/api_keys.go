@@ -0,0 +1,115 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sangtandoan/social/internal/middleware"
+	"github.com/sangtandoan/social/internal/models/dto"
+	"github.com/sangtandoan/social/internal/models/params"
+	"github.com/sangtandoan/social/internal/service/auth"
+	"github.com/sangtandoan/social/internal/store"
+	"github.com/sangtandoan/social/internal/utils"
+)
+
+// The full key is only part of this response, afterwards it is recognized by its prefix
+type createAPIKeyResponse struct {
+	*store.APIKey
+	Key string `json:"key"`
+}
+
+func (a *application) createAPIKeyHandler(c *gin.Context) {
+	var req dto.CreateAPIKeyRequest
+
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		c.Error(utils.ErrInvalidJSON)
+		return
+	}
+
+	err = utils.Validator.Struct(&req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	for _, scope := range req.Scopes {
+		if !auth.IsValidScope(scope) {
+			c.Error(utils.NewApiError(http.StatusBadRequest, "unknown scope "+scope))
+			return
+		}
+	}
+
+	userID, ok := middleware.GetUserID(c.Request.Context())
+	if !ok {
+		c.Error(utils.ErrUnauthorized)
+		return
+	}
+
+	key, prefix, err := auth.GenerateAPIKey()
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	arg := params.CreateAPIKeyParams{
+		UserID:  userID,
+		Name:    req.Name,
+		Prefix:  prefix,
+		KeyHash: utils.HashToken(key),
+		Scopes:  req.Scopes,
+	}
+	if req.ExpiresInDays > 0 {
+		expiresAt := time.Now().AddDate(0, 0, req.ExpiresInDays)
+		arg.ExpiresAt = &expiresAt
+	}
+
+	apiKey, err := a.store.APIKeys.Create(c.Request.Context(), &arg)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	res := createAPIKeyResponse{APIKey: apiKey, Key: key}
+	c.JSON(http.StatusCreated, utils.NewApiResponse("created api key successfully", res))
+}
+
+func (a *application) getAPIKeysHandler(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c.Request.Context())
+	if !ok {
+		c.Error(utils.ErrUnauthorized)
+		return
+	}
+
+	keys, err := a.store.APIKeys.ListByUserID(c.Request.Context(), userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.NewApiResponse("fetch api keys successfully", keys))
+}
+
+func (a *application) deleteAPIKeyHandler(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c.Request.Context())
+	if !ok {
+		c.Error(utils.ErrUnauthorized)
+		return
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.Error(utils.ErrNotFound)
+		return
+	}
+
+	err = a.store.APIKeys.Delete(c.Request.Context(), userID, id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.NewApiResponse("deleted api key successfully", nil))
+}
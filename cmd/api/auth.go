@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sangtandoan/social/internal/models/dto"
+	"github.com/sangtandoan/social/internal/models/params"
+	"github.com/sangtandoan/social/internal/store"
+	"github.com/sangtandoan/social/internal/utils"
+)
+
+// newSession describes the client a new token family is issued to
+func newSession(c *gin.Context, userID int64) *params.CreateRefreshTokenParams {
+	return &params.CreateRefreshTokenParams{
+		UserID:    userID,
+		FamilyID:  uuid.New().String(),
+		IPAddress: c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	}
+}
+
+// issueTokens signs a new access token and persists a new refresh token in the given family
+func (a *application) issueTokens(
+	ctx context.Context,
+	session *params.CreateRefreshTokenParams,
+) (*dto.LoginResponse, error) {
+	accessToken, accessExpiresAt, err := a.tokenMaker.CreateAccessToken(
+		session.UserID,
+		session.FamilyID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, refreshExpiresAt, err := a.tokenMaker.CreateRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+
+	session.Token = utils.HashToken(refreshToken)
+	session.ExpiresAt = refreshExpiresAt
+
+	_, err = a.store.RefreshTokens.Create(ctx, session)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.LoginResponse{
+		AccessToken:           accessToken,
+		AccessTokenExpiresAt:  accessExpiresAt,
+		RefreshToken:          refreshToken,
+		RefreshTokenExpiresAt: refreshExpiresAt,
+	}, nil
+}
+
+// Every refresh rotates the token. Presenting a token that was already rotated means
+// it leaked, so the whole family is revoked and the client has to login again. A token
+// revoked by signing out is only rejected.
+func (a *application) refreshTokenHandler(c *gin.Context) {
+	var req dto.RefreshTokenRequest
+
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		c.Error(utils.ErrInvalidJSON)
+		return
+	}
+
+	err = utils.Validator.Struct(&req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	var res *dto.LoginResponse
+	reused := false
+
+	err = a.store.Tx.WithTx(c.Request.Context(), func(txCtx context.Context) error {
+		token, err := a.store.RefreshTokens.GetByToken(txCtx, utils.HashToken(req.RefreshToken))
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return utils.ErrInvalidToken
+			}
+			return err
+		}
+
+		// A token of a signed out session is just stale, and the family of a reused
+		// one is already revoked
+		if token.IsRevoked && token.RevokedReason != store.RevokedRotated {
+			return utils.ErrInvalidToken
+		}
+
+		if token.IsRevoked {
+			reused = true
+
+			err = a.store.RefreshTokens.RecordReuse(txCtx, &params.RecordTokenReuseParams{
+				ID:        token.ID,
+				IPAddress: c.ClientIP(),
+				UserAgent: c.Request.UserAgent(),
+			})
+			if err != nil {
+				return err
+			}
+
+			return a.store.RefreshTokens.RevokeFamily(txCtx, token.FamilyID)
+		}
+
+		if time.Now().After(token.ExpiresAt) {
+			return utils.ErrInvalidToken
+		}
+
+		err = a.store.RefreshTokens.Revoke(txCtx, token.ID)
+		if err != nil {
+			return err
+		}
+
+		res, err = a.issueTokens(txCtx, &params.CreateRefreshTokenParams{
+			UserID:    token.UserID,
+			FamilyID:  token.FamilyID,
+			IPAddress: c.ClientIP(),
+			UserAgent: c.Request.UserAgent(),
+		})
+		return err
+	})
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	if reused {
+		utils.Log.Warnf(
+			"refresh token reuse detected from ip %s, user agent %q",
+			c.ClientIP(),
+			c.Request.UserAgent(),
+		)
+		c.Error(utils.ErrTokenReused)
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.NewApiResponse("refresh token successfully", res))
+}
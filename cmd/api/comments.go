@@ -0,0 +1,245 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sangtandoan/social/internal/middleware"
+	"github.com/sangtandoan/social/internal/models/dto"
+	"github.com/sangtandoan/social/internal/store"
+	"github.com/sangtandoan/social/internal/utils"
+)
+
+// defaultThreadDepth is how many levels of replies come with a comment unless asked otherwise
+const defaultThreadDepth = 2
+
+func (a *application) getCommentsHandler(c *gin.Context) error {
+	post, err := a.getReadablePost(c)
+	if err != nil {
+		return err
+	}
+
+	page := dto.Pagination{Limit: defaultPageLimit}
+	if err := c.ShouldBindQuery(&page); err != nil {
+		return utils.NewApiError(http.StatusBadRequest, "invalid query")
+	}
+
+	if err := a.decodePage(&page); err != nil {
+		return err
+	}
+
+	thread, err := bindThread(c)
+	if err != nil {
+		return err
+	}
+
+	comments, next, err := a.store.Comments.ListByPostID(c.Request.Context(), int64(post.ID), &page)
+	if err != nil {
+		return err
+	}
+
+	err = a.store.Comments.AttachReplies(c.Request.Context(), comments, thread.Depth)
+	if err != nil {
+		return err
+	}
+
+	c.JSON(
+		http.StatusOK,
+		utils.NewPageResponse("fetch comments successfully", comments, a.encodeCursor(next)),
+	)
+	return nil
+}
+
+// getCommentRepliesHandler loads a branch of a thread that was cut at the requested depth,
+// or the replies a thread left out: the direct replies come a page at a time, oldest first
+func (a *application) getCommentRepliesHandler(c *gin.Context) error {
+	post, err := a.getReadablePost(c)
+	if err != nil {
+		return err
+	}
+
+	commentID, err := strconv.ParseInt(c.Param("commentID"), 10, 64)
+	if err != nil {
+		return utils.ErrNotFound
+	}
+
+	page := dto.Pagination{Limit: defaultPageLimit}
+	if err := c.ShouldBindQuery(&page); err != nil {
+		return utils.NewApiError(http.StatusBadRequest, "invalid query")
+	}
+
+	if err := a.decodePage(&page); err != nil {
+		return err
+	}
+
+	thread, err := bindThread(c)
+	if err != nil {
+		return err
+	}
+
+	comment, err := a.store.Comments.Get(c.Request.Context(), commentID, int64(post.ID))
+	if err != nil {
+		return err
+	}
+
+	replies, next, err := a.store.Comments.ListReplies(c.Request.Context(), comment.ID, &page)
+	if err != nil {
+		return err
+	}
+
+	// The page is the first level of the branch
+	err = a.store.Comments.AttachReplies(c.Request.Context(), replies, max(thread.Depth, 1)-1)
+	if err != nil {
+		return err
+	}
+	comment.Replies = replies
+
+	c.JSON(
+		http.StatusOK,
+		utils.NewPageResponse("fetch replies successfully", comment, a.encodeCursor(next)),
+	)
+	return nil
+}
+
+func (a *application) createCommentHandler(c *gin.Context) error {
+	post, err := a.getReadablePost(c)
+	if err != nil {
+		return err
+	}
+
+	userID, ok := middleware.GetUserID(c.Request.Context())
+	if !ok {
+		return utils.ErrUnauthorized
+	}
+
+	req, err := bindComment(c)
+	if err != nil {
+		return err
+	}
+
+	comment := &store.Comment{
+		PostID:   int64(post.ID),
+		UserID:   userID,
+		ParentID: req.ParentID,
+		Content:  req.Content,
+	}
+
+	err = a.store.Comments.Create(c.Request.Context(), comment)
+	if err != nil {
+		return err
+	}
+
+	c.JSON(http.StatusCreated, utils.NewApiResponse("created comment successfully", comment))
+	return nil
+}
+
+func (a *application) updateCommentHandler(c *gin.Context) error {
+	post, err := a.getReadablePost(c)
+	if err != nil {
+		return err
+	}
+
+	commentID, err := strconv.ParseInt(c.Param("commentID"), 10, 64)
+	if err != nil {
+		return utils.ErrNotFound
+	}
+
+	userID, ok := middleware.GetUserID(c.Request.Context())
+	if !ok {
+		return utils.ErrUnauthorized
+	}
+
+	req, err := bindComment(c)
+	if err != nil {
+		return err
+	}
+
+	comment, err := a.store.Comments.Update(
+		c.Request.Context(),
+		commentID,
+		int64(post.ID),
+		userID,
+		req.Content,
+	)
+	if err != nil {
+		return err
+	}
+
+	c.JSON(http.StatusOK, utils.NewApiResponse("updated comment successfully", comment))
+	return nil
+}
+
+func (a *application) deleteCommentHandler(c *gin.Context) error {
+	post, err := a.getReadablePost(c)
+	if err != nil {
+		return err
+	}
+
+	commentID, err := strconv.ParseInt(c.Param("commentID"), 10, 64)
+	if err != nil {
+		return utils.ErrNotFound
+	}
+
+	userID, ok := middleware.GetUserID(c.Request.Context())
+	if !ok {
+		return utils.ErrUnauthorized
+	}
+
+	err = a.store.Comments.Delete(c.Request.Context(), commentID, int64(post.ID), userID)
+	if err != nil {
+		return err
+	}
+
+	c.JSON(http.StatusOK, utils.NewApiResponse("deleted comment successfully", nil))
+	return nil
+}
+
+// getReadablePost loads the post of a comment route, comments of a post the
+// caller can not read do not exist for them either
+func (a *application) getReadablePost(c *gin.Context) (*store.Post, error) {
+	postID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return nil, utils.ErrNotFound
+	}
+
+	post, err := a.store.Posts.GetByID(c.Request.Context(), postID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := a.authorizePostRead(c, post); err != nil {
+		return nil, err
+	}
+
+	return post, nil
+}
+
+func bindComment(c *gin.Context) (*dto.CommentRequest, error) {
+	var req dto.CommentRequest
+	if err := utils.ReadJSON(c, &req); err != nil {
+		return nil, utils.ErrInvalidJSON
+	}
+
+	if err := utils.Validator.Struct(&req); err != nil {
+		return nil, utils.NewApiError(
+			http.StatusBadRequest,
+			"content is required and at most 2000 characters",
+		)
+	}
+
+	return &req, nil
+}
+
+func bindThread(c *gin.Context) (*dto.ThreadRequest, error) {
+	thread := dto.ThreadRequest{Depth: defaultThreadDepth}
+	if err := c.ShouldBindQuery(&thread); err != nil {
+		return nil, utils.NewApiError(http.StatusBadRequest, "invalid query")
+	}
+
+	if err := utils.Validator.Struct(&thread); err != nil {
+		return nil, utils.NewApiError(http.StatusBadRequest, "depth must be between 0 and 5")
+	}
+
+	return &thread, nil
+}
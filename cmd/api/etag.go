@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+
+	"github.com/sangtandoan/social/internal/store"
+	"github.com/sangtandoan/social/internal/utils"
+)
+
+// postETag is the post's version followed by a hash of its reaction counts. The
+// counts change the representation, so cached copies must be revalidated, but they
+// are not an edit, so If-Match only compares the version.
+func postETag(post *store.Post) string {
+	// Keys are sorted and no counts read as {}, so equal counts hash the same
+	counts, _ := post.ReactionCounts.Value()
+
+	hash := fnv.New32a()
+	hash.Write([]byte(counts.(string)))
+
+	return fmt.Sprintf(`"%d-%08x"`, post.Version, hash.Sum32())
+}
+
+// parseIfMatch returns the versions listed in an If-Match header, none when the
+// header is missing or *. If-Match compares strongly, so weak tags never match.
+// The reaction counts part of a tag is ignored.
+func parseIfMatch(header string) ([]int64, error) {
+	header = strings.TrimSpace(header)
+	if header == "" || header == "*" {
+		return nil, nil
+	}
+
+	var versions []int64
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if strings.HasPrefix(tag, "W/") {
+			continue
+		}
+
+		tag, _, _ = strings.Cut(strings.Trim(tag, `"`), "-")
+		version, err := strconv.ParseInt(tag, 10, 64)
+		if err != nil {
+			continue
+		}
+		versions = append(versions, version)
+	}
+
+	if len(versions) == 0 {
+		return nil, utils.ErrPreconditionFailed
+	}
+
+	return versions, nil
+}
+
+// noneMatch reports whether an If-None-Match header lists the etag, with the
+// weak comparison the header calls for
+func noneMatch(header, etag string) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/sangtandoan/social/internal/store"
+)
+
+func TestPostETagFollowsReactions(t *testing.T) {
+	post := &store.Post{Version: 3}
+	unreacted := postETag(post)
+
+	post.ReactionCounts = store.ReactionCounts{}
+	if got := postETag(post); got != unreacted {
+		t.Fatalf("empty counts: etag = %s, want %s", got, unreacted)
+	}
+
+	post.ReactionCounts = store.ReactionCounts{"like": 1}
+	reacted := postETag(post)
+	if reacted == unreacted {
+		t.Fatal("a reaction did not change the etag")
+	}
+
+	// A reaction is not an edit, the tag still matches the version it was read at
+	versions, err := parseIfMatch(reacted + `, W/"7", "x"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(versions, []int64{3}) {
+		t.Fatalf("If-Match versions = %v, want [3]", versions)
+	}
+
+	if !noneMatch(`"1-00000000", `+reacted, reacted) {
+		t.Fatal("If-None-Match did not match the current etag")
+	}
+	if noneMatch(unreacted, reacted) {
+		t.Fatal("If-None-Match matched the etag from before the reaction")
+	}
+}
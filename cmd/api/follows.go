@@ -0,0 +1,248 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sangtandoan/social/internal/middleware"
+	"github.com/sangtandoan/social/internal/models/dto"
+	"github.com/sangtandoan/social/internal/store"
+	"github.com/sangtandoan/social/internal/utils"
+)
+
+// Following yourself or someone twice is rejected by the database, the
+// GlobalErrorHandler turns those errors into 4xx responses. Following a
+// private account only asks to, until the account approves.
+func (a *application) followUserHandler(c *gin.Context) {
+	target, ok := a.getReadableUser(c)
+	if !ok {
+		return
+	}
+
+	userID, ok := middleware.GetUserID(c.Request.Context())
+	if !ok {
+		c.Error(utils.ErrUnauthorized)
+		return
+	}
+
+	pending, err := a.store.Followers.Follow(c.Request.Context(), &store.FollowParams{
+		UserID:     target.ID,
+		FollowerID: userID,
+	})
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	if pending {
+		c.JSON(http.StatusAccepted, utils.NewApiResponse("sent follow request successfully", nil))
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.NewApiResponse("followed user successfully", nil))
+}
+
+func (a *application) unfollowUserHandler(c *gin.Context) {
+	target, ok := a.getReadableUser(c)
+	if !ok {
+		return
+	}
+
+	userID, ok := middleware.GetUserID(c.Request.Context())
+	if !ok {
+		c.Error(utils.ErrUnauthorized)
+		return
+	}
+
+	err := a.store.Followers.Unfollow(c.Request.Context(), &store.UnfollowParams{
+		UserID:     target.ID,
+		FollowerID: userID,
+	})
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.NewApiResponse("unfollowed user successfully", nil))
+}
+
+func (a *application) getFollowersHandler(c *gin.Context) {
+	a.listFollows(c, "fetch followers successfully", a.store.Followers.ListFollowers)
+}
+
+func (a *application) getFollowingHandler(c *gin.Context) {
+	a.listFollows(c, "fetch following successfully", a.store.Followers.ListFollowing)
+}
+
+type listFollowsFunc func(
+	ctx context.Context,
+	userID int64,
+	page *dto.Pagination,
+) ([]*store.FollowUser, *utils.Cursor, error)
+
+func (a *application) listFollows(c *gin.Context, msg string, list listFollowsFunc) {
+	user, ok := a.getReadableUser(c)
+	if !ok {
+		return
+	}
+
+	page := dto.Pagination{Limit: defaultPageLimit}
+	if err := c.ShouldBindQuery(&page); err != nil {
+		c.Error(utils.NewApiError(http.StatusBadRequest, "invalid query"))
+		return
+	}
+
+	if err := a.decodePage(&page); err != nil {
+		c.Error(err)
+		return
+	}
+
+	users, next, err := list(c.Request.Context(), user.ID, &page)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.NewPageResponse(msg, users, a.encodeCursor(next)))
+}
+
+// cancelFollowRequestHandler withdraws the caller's pending request to follow :id
+func (a *application) cancelFollowRequestHandler(c *gin.Context) {
+	target, ok := a.getReadableUser(c)
+	if !ok {
+		return
+	}
+
+	userID, ok := middleware.GetUserID(c.Request.Context())
+	if !ok {
+		c.Error(utils.ErrUnauthorized)
+		return
+	}
+
+	err := a.store.FollowRequests.Delete(c.Request.Context(), target.ID, userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.NewApiResponse("cancelled follow request successfully", nil))
+}
+
+func (a *application) getFollowRequestsHandler(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c.Request.Context())
+	if !ok {
+		c.Error(utils.ErrUnauthorized)
+		return
+	}
+
+	page := dto.Pagination{Limit: defaultPageLimit}
+	if err := c.ShouldBindQuery(&page); err != nil {
+		c.Error(utils.NewApiError(http.StatusBadRequest, "invalid query"))
+		return
+	}
+
+	if err := a.decodePage(&page); err != nil {
+		c.Error(err)
+		return
+	}
+
+	requests, next, err := a.store.FollowRequests.ListIncoming(c.Request.Context(), userID, &page)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(
+		http.StatusOK,
+		utils.NewPageResponse("fetch follow requests successfully", requests, a.encodeCursor(next)),
+	)
+}
+
+func (a *application) approveFollowRequestHandler(c *gin.Context) {
+	userID, requesterID, ok := getFollowRequestIDs(c)
+	if !ok {
+		return
+	}
+
+	err := a.store.FollowRequests.Approve(c.Request.Context(), userID, requesterID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.NewApiResponse("approved follow request successfully", nil))
+}
+
+func (a *application) rejectFollowRequestHandler(c *gin.Context) {
+	userID, requesterID, ok := getFollowRequestIDs(c)
+	if !ok {
+		return
+	}
+
+	err := a.store.FollowRequests.Delete(c.Request.Context(), userID, requesterID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.NewApiResponse("rejected follow request successfully", nil))
+}
+
+// getFollowRequestIDs reads the caller and the requester :id of an incoming request route
+func getFollowRequestIDs(c *gin.Context) (int64, int64, bool) {
+	requesterID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.Error(utils.ErrNotFound)
+		return 0, 0, false
+	}
+
+	userID, ok := middleware.GetUserID(c.Request.Context())
+	if !ok {
+		c.Error(utils.ErrUnauthorized)
+		return 0, 0, false
+	}
+
+	return userID, requesterID, true
+}
+
+// setPrivacyHandler makes the caller's account private or public. Going public
+// approves the pending requests, as nobody would be left to approve them.
+func (a *application) setPrivacyHandler(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c.Request.Context())
+	if !ok {
+		c.Error(utils.ErrUnauthorized)
+		return
+	}
+
+	var req dto.PrivacyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(utils.ErrInvalidJSON)
+		return
+	}
+
+	if err := utils.Validator.Struct(&req); err != nil {
+		c.Error(utils.NewApiError(http.StatusBadRequest, "private is required"))
+		return
+	}
+
+	err := a.store.Tx.WithTx(c.Request.Context(), func(txCtx context.Context) error {
+		err := a.store.Users.SetPrivate(txCtx, userID, *req.Private)
+		if err != nil {
+			return err
+		}
+
+		if *req.Private {
+			return nil
+		}
+
+		return a.store.FollowRequests.ApproveAll(txCtx, userID)
+	})
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.NewApiResponse("updated privacy successfully", nil))
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/sangtandoan/social/internal/store"
+	"github.com/sangtandoan/social/internal/utils"
+)
+
+const (
+	purgeInterval  = time.Hour
+	purgeBatchSize = 500
+
+	publishInterval  = 30 * time.Second
+	publishBatchSize = 100
+
+	reconcileInterval = 24 * time.Hour
+)
+
+// purgeDeletedPosts removes the posts whose restore window is over. Every instance
+// runs it, deleting the same rows twice is harmless.
+func (a *application) purgeDeletedPosts(ctx context.Context) {
+	ticker := time.NewTicker(purgeInterval)
+	defer ticker.Stop()
+
+	for {
+		before := time.Now().Add(-store.PostRestoreWindow)
+
+		var total int64
+		for {
+			purged, err := a.store.Posts.PurgeDeleted(ctx, before, purgeBatchSize)
+			if err != nil {
+				utils.Log.Errorf("can not purge deleted posts: %v", err)
+				break
+			}
+
+			total += purged
+			// Small batches keep each statement short, stop once a batch is not full
+			if purged < purgeBatchSize {
+				break
+			}
+		}
+
+		if total > 0 {
+			utils.Log.Infof("purged %d deleted posts", total)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// publishScheduledPosts publishes the scheduled posts that are due. Every instance
+// runs it, the rows are claimed with SKIP LOCKED so each post is published once.
+func (a *application) publishScheduledPosts(ctx context.Context) {
+	ticker := time.NewTicker(publishInterval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			ids, err := a.store.Posts.PublishDue(ctx, publishBatchSize)
+			if err != nil {
+				utils.Log.Errorf("can not publish scheduled posts: %v", err)
+				break
+			}
+
+			// A draft of the post may still be cached for its author
+			for _, id := range ids {
+				a.evictPost(ctx, strconv.FormatInt(id, 10))
+			}
+
+			if len(ids) > 0 {
+				utils.Log.Infof("published %d scheduled posts", len(ids))
+			}
+			if len(ids) < publishBatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// reconcileReactions corrects the reaction counts that drifted from the reactions,
+// toggles keep them exact so this only catches what cascades around them
+func (a *application) reconcileReactions(ctx context.Context) {
+	ticker := time.NewTicker(reconcileInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		for _, target := range []store.ReactionTarget{
+			store.ReactionTargetPost,
+			store.ReactionTargetComment,
+		} {
+			fixed, err := a.store.Reactions.Reconcile(ctx, target)
+			if err != nil {
+				utils.Log.Errorf("can not reconcile reaction counts: %v", err)
+				continue
+			}
+
+			if len(fixed) > 0 {
+				utils.Log.Infof("reconciled the reaction counts of %d rows", len(fixed))
+			}
+
+			// The cached copies of the posts carry the counts
+			if target == store.ReactionTargetPost {
+				for _, id := range fixed {
+					a.evictPost(ctx, strconv.FormatInt(id, 10))
+				}
+			}
+		}
+	}
+}
@@ -1,16 +1,30 @@
 package main
 
 import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
 	"github.com/go-playground/validator/v10"
 	"github.com/sangtandoan/social/internal/config"
 	"github.com/sangtandoan/social/internal/db"
 	"github.com/sangtandoan/social/internal/service"
+	"github.com/sangtandoan/social/internal/service/abac"
+	"github.com/sangtandoan/social/internal/service/auth"
 	"github.com/sangtandoan/social/internal/service/cache"
+	"github.com/sangtandoan/social/internal/service/oauth"
 	"github.com/sangtandoan/social/internal/store"
 	"github.com/sangtandoan/social/internal/utils"
 	"go.uber.org/zap"
 )
 
+const (
+	// Policy changes made on another instance show up here after at most this long
+	policyRefreshInterval = time.Minute
+	ruleFileCheckInterval = 5 * time.Second
+)
+
 func main() {
 	config := config.LoadCfg()
 
@@ -39,9 +53,65 @@ func main() {
 
 	store := store.NewStore(db)
 
-	app := application{config, store, mailer, cache, nil}
+	tokenMaker := auth.NewTokenMaker(config.AuthConfig)
+
+	oauthProviders := map[string]oauth.Provider{}
+	if config.OAuthConfig.GoogleClientID != "" {
+		oauthProviders["google"] = oauth.NewGoogleProvider(
+			config.OAuthConfig.GoogleClientID,
+			config.OAuthConfig.GoogleClientSecret,
+		)
+	}
+
+	var fakeIssuer *oauth.FakeIssuer
+	if config.OAuthConfig.FakeProvider {
+		utils.Log.Warn("fake oauth provider is enabled, never use it in production")
+
+		fakeIssuer = oauth.NewFakeIssuer(fakeIssuerURL(config.OAuthConfig.RedirectURL))
+		oauthProviders["fake"] = fakeIssuer.Provider("fake")
+	}
+
+	policyEngine := abac.NewEngine(
+		store.Policies,
+		policyRefreshInterval,
+		abac.NewUserProvider(store.Users, store.Roles),
+		abac.NewPostProvider(store.Posts),
+		abac.NewRelationProvider(store.Followers),
+	)
+
+	if config.PolicyFile != "" {
+		ruleFile, err := abac.LoadRuleFile(config.PolicyFile)
+		if err != nil {
+			utils.Log.Panic("Failed to load policy file: ", err)
+		}
+
+		go ruleFile.Watch(context.Background(), ruleFileCheckInterval)
+		policyEngine.UseRules(ruleFile)
+	}
+
+	app := application{
+		config,
+		store,
+		mailer,
+		cache,
+		tokenMaker,
+		oauthProviders,
+		fakeIssuer,
+		policyEngine,
+		nil,
+	}
 
 	mux := app.mount()
 
 	utils.Log.Fatal(app.run(mux))
 }
+
+// The fake issuer is served by this server, next to the callback
+func fakeIssuerURL(redirectURL string) string {
+	u, err := url.Parse(redirectURL)
+	if err != nil {
+		return "/oauth/fake"
+	}
+
+	return fmt.Sprintf("%s://%s/oauth/fake", u.Scheme, u.Host)
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sangtandoan/social/internal/middleware"
+	"github.com/sangtandoan/social/internal/models/dto"
+	"github.com/sangtandoan/social/internal/service/auth"
+	"github.com/sangtandoan/social/internal/store"
+	"github.com/sangtandoan/social/internal/utils"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const recoveryCodesCount = 10
+
+// mfaChallenge answers the first half of a login for accounts with two-factor enabled
+func (a *application) mfaChallenge(c *gin.Context, user *store.User) {
+	token, expiresAt, err := a.tokenMaker.CreateMFAToken(user.ID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	res := dto.MFAChallengeResponse{
+		MFARequired: true,
+		MFAToken:    token,
+		ExpiresAt:   expiresAt,
+	}
+
+	c.JSON(http.StatusOK, utils.NewApiResponse("two-factor authentication required", res))
+}
+
+func (a *application) mfaLoginHandler(c *gin.Context) {
+	var req dto.MFALoginRequest
+
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		c.Error(utils.ErrInvalidJSON)
+		return
+	}
+
+	err = utils.Validator.Struct(&req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	claims, err := a.tokenMaker.VerifyMFAToken(req.MFAToken)
+	if err != nil {
+		c.Error(utils.ErrInvalidToken)
+		return
+	}
+
+	user, err := a.store.Users.GetByID(c.Request.Context(), claims.UserID)
+	if err != nil {
+		c.Error(utils.ErrInvalidToken)
+		return
+	}
+
+	if user.LockedUntil != nil && time.Now().Before(*user.LockedUntil) {
+		c.Error(utils.ErrAccountLocked)
+		return
+	}
+
+	if !user.MFAEnabled {
+		c.Error(utils.ErrInvalidToken)
+		return
+	}
+
+	err = a.verifySecondFactor(c.Request.Context(), user, req.Code, req.RecoveryCode)
+	if err != nil {
+		if errors.Is(err, utils.ErrInvalidMFACode) {
+			if err := a.recordFailedLogin(c, user); err != nil {
+				c.Error(err)
+				return
+			}
+		}
+		c.Error(err)
+		return
+	}
+
+	a.completeLogin(c, user)
+}
+
+// verifySecondFactor accepts either a current totp code or an unused recovery code
+func (a *application) verifySecondFactor(
+	ctx context.Context,
+	user *store.User,
+	code, recoveryCode string,
+) error {
+	if code == "" {
+		recoveryCode = strings.ToLower(strings.TrimSpace(recoveryCode))
+		return a.store.RecoveryCodes.Consume(ctx, user.ID, utils.HashToken(recoveryCode))
+	}
+
+	step, ok := auth.ValidateTOTP(user.MFASecret, code, time.Now())
+	if !ok {
+		return utils.ErrInvalidMFACode
+	}
+
+	// A code seen on the wire must not work a second time
+	fresh, err := a.store.Users.UseTOTPStep(ctx, user.ID, step)
+	if err != nil {
+		return err
+	}
+
+	if !fresh {
+		return utils.ErrInvalidMFACode
+	}
+
+	return nil
+}
+
+// Enrollment stays pending until the first code is confirmed, so a user who never
+// finishes the setup is not locked out of their account
+func (a *application) enrollMFAHandler(c *gin.Context) {
+	user, ok := a.getCurrentUser(c)
+	if !ok {
+		return
+	}
+
+	if user.MFAEnabled {
+		c.Error(utils.ErrMFAAlreadyEnabled)
+		return
+	}
+
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	err = a.store.Users.SetPendingMFASecret(c.Request.Context(), user.ID, secret)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	issuer := a.config.AuthConfig.Issuer
+	if issuer == "" {
+		issuer = "social"
+	}
+
+	res := dto.MFAEnrollResponse{
+		Secret: secret,
+		URI:    auth.TOTPURI(issuer, user.Email, secret),
+	}
+
+	c.JSON(http.StatusOK, utils.NewApiResponse("scan the code with your authenticator app", res))
+}
+
+// The recovery codes are only returned here, the server keeps their hashes
+func (a *application) confirmMFAHandler(c *gin.Context) {
+	var req dto.MFAConfirmRequest
+
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		c.Error(utils.ErrInvalidJSON)
+		return
+	}
+
+	err = utils.Validator.Struct(&req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	user, ok := a.getCurrentUser(c)
+	if !ok {
+		return
+	}
+
+	if user.MFAEnabled {
+		c.Error(utils.ErrMFAAlreadyEnabled)
+		return
+	}
+
+	if user.MFASecret == "" {
+		c.Error(utils.ErrMFANotEnrolled)
+		return
+	}
+
+	codes, err := auth.GenerateRecoveryCodes(recoveryCodesCount)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	hashedCodes := make([]string, len(codes))
+	for i, code := range codes {
+		hashedCodes[i] = utils.HashToken(code)
+	}
+
+	err = a.store.Tx.WithTx(c.Request.Context(), func(txCtx context.Context) error {
+		err := a.verifySecondFactor(txCtx, user, req.Code, "")
+		if err != nil {
+			return err
+		}
+
+		err = a.store.Users.EnableMFA(txCtx, user.ID)
+		if err != nil {
+			return err
+		}
+
+		return a.store.RecoveryCodes.ReplaceAll(txCtx, user.ID, hashedCodes)
+	})
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	res := dto.MFAConfirmResponse{RecoveryCodes: codes}
+	c.JSON(http.StatusOK, utils.NewApiResponse("two-factor authentication enabled", res))
+}
+
+// Turning the second factor off requires the password and a second factor again,
+// a stolen access token alone is not enough
+func (a *application) disableMFAHandler(c *gin.Context) {
+	var req dto.MFADisableRequest
+
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		c.Error(utils.ErrInvalidJSON)
+		return
+	}
+
+	err = utils.Validator.Struct(&req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	user, ok := a.getCurrentUser(c)
+	if !ok {
+		return
+	}
+
+	if !user.MFAEnabled {
+		c.Error(utils.ErrMFANotEnabled)
+		return
+	}
+
+	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password))
+	if err != nil {
+		if err := a.recordFailedLogin(c, user); err != nil {
+			c.Error(err)
+			return
+		}
+		c.Error(utils.ErrUnauthorized)
+		return
+	}
+
+	err = a.store.Tx.WithTx(c.Request.Context(), func(txCtx context.Context) error {
+		err := a.verifySecondFactor(txCtx, user, req.Code, req.RecoveryCode)
+		if err != nil {
+			return err
+		}
+
+		err = a.store.Users.DisableMFA(txCtx, user.ID)
+		if err != nil {
+			return err
+		}
+
+		return a.store.RecoveryCodes.DeleteByUserID(txCtx, user.ID)
+	})
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.NewApiResponse("two-factor authentication disabled", nil))
+}
+
+// getCurrentUser loads the authenticated user, errors are already attached to the context
+func (a *application) getCurrentUser(c *gin.Context) (*store.User, bool) {
+	userID, ok := middleware.GetUserID(c.Request.Context())
+	if !ok {
+		c.Error(utils.ErrUnauthorized)
+		return nil, false
+	}
+
+	user, err := a.store.Users.GetByID(c.Request.Context(), userID)
+	if err != nil {
+		c.Error(err)
+		return nil, false
+	}
+
+	return user, true
+}
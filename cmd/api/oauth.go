@@ -0,0 +1,263 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sangtandoan/social/internal/models/dto"
+	"github.com/sangtandoan/social/internal/models/params"
+	"github.com/sangtandoan/social/internal/service/oauth"
+	"github.com/sangtandoan/social/internal/store"
+	"github.com/sangtandoan/social/internal/utils"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const oauthStateExpiration = 10 * time.Minute
+
+type oauthState struct {
+	Provider     string `json:"provider"`
+	CodeVerifier string `json:"code_verifier"`
+}
+
+func (a *application) oauthRedirectURI(provider string) string {
+	return strings.TrimSuffix(a.config.OAuthConfig.RedirectURL, "/") + "/" + provider + "/callback"
+}
+
+// The state and the PKCE verifier never leave the server, only the challenge is sent
+func (a *application) oauthStartHandler(c *gin.Context) {
+	provider, ok := a.oauthProviders[c.Param("provider")]
+	if !ok {
+		c.Error(utils.ErrNotFound)
+		return
+	}
+
+	state, err := oauth.GenerateVerifier()
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	verifier, err := oauth.GenerateVerifier()
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	data, _ := json.Marshal(&oauthState{Provider: provider.Name(), CodeVerifier: verifier})
+	err = a.cache.Set(c.Request.Context(), oauthStateKey(state), data, oauthStateExpiration)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	authURL := provider.AuthCodeURL(
+		state,
+		oauth.S256Challenge(verifier),
+		a.oauthRedirectURI(provider.Name()),
+	)
+	if hint := c.Query("login_hint"); hint != "" {
+		authURL += "&login_hint=" + url.QueryEscape(hint)
+	}
+
+	res := dto.OAuthStartResponse{AuthorizationURL: authURL}
+	c.JSON(http.StatusOK, utils.NewApiResponse("redirect to the provider", res))
+}
+
+func (a *application) oauthCallbackHandler(c *gin.Context) {
+	provider, ok := a.oauthProviders[c.Param("provider")]
+	if !ok {
+		c.Error(utils.ErrNotFound)
+		return
+	}
+
+	if c.Query("error") != "" {
+		c.Error(utils.ErrOAuthFailed)
+		return
+	}
+
+	code, stateParam := c.Query("code"), c.Query("state")
+	if code == "" || stateParam == "" {
+		c.Error(utils.ErrOAuthInvalidState)
+		return
+	}
+
+	data, err := a.cache.GetDel(c.Request.Context(), oauthStateKey(stateParam))
+	if err != nil {
+		c.Error(utils.ErrOAuthInvalidState)
+		return
+	}
+
+	var state oauthState
+	if err := json.Unmarshal([]byte(data), &state); err != nil || state.Provider != provider.Name() {
+		c.Error(utils.ErrOAuthInvalidState)
+		return
+	}
+
+	identity, err := provider.Exchange(
+		c.Request.Context(),
+		code,
+		state.CodeVerifier,
+		a.oauthRedirectURI(provider.Name()),
+	)
+	if err != nil {
+		utils.Log.Warnf("oauth exchange with %s failed: %v", provider.Name(), err)
+		c.Error(utils.ErrOAuthFailed)
+		return
+	}
+
+	var user *store.User
+	err = a.store.Tx.WithTx(c.Request.Context(), func(txCtx context.Context) error {
+		user, err = a.resolveOAuthUser(txCtx, provider.Name(), identity)
+		return err
+	})
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	if user.LockedUntil != nil && time.Now().Before(*user.LockedUntil) {
+		c.Error(utils.ErrAccountLocked)
+		return
+	}
+
+	if user.MFAEnabled {
+		a.mfaChallenge(c, user)
+		return
+	}
+
+	a.completeLogin(c, user)
+}
+
+// resolveOAuthUser returns the user linked to the identity. An unknown identity is
+// linked to the account with the same email, or a new account is created, but only
+// when the provider verified the email. Otherwise anyone could register an email they
+// don't own at some provider and take over the account.
+func (a *application) resolveOAuthUser(
+	ctx context.Context,
+	provider string,
+	identity *oauth.Identity,
+) (*store.User, error) {
+	userID, err := a.store.Identities.GetUserID(ctx, provider, identity.Subject)
+	if err == nil {
+		return a.store.Users.GetByID(ctx, userID)
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	if !identity.EmailVerified || identity.Email == "" {
+		return nil, utils.ErrOAuthEmailNotVerified
+	}
+
+	user, err := a.store.Users.GetByEmail(ctx, identity.Email)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+
+		user, err = a.createOAuthUser(ctx, identity)
+		if err != nil {
+			return nil, err
+		}
+	} else if !user.Active {
+		// Whoever registered the email never proved they own it. Their password and
+		// sessions must not open the account once the provider's user activates it.
+		err = a.revokeUnclaimedAccount(ctx, user.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// The provider proved the ownership of the email
+	err = a.store.Users.Activate(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	user.Active = true
+
+	err = a.store.Identities.Create(ctx, &params.CreateIdentityParams{
+		UserID:   user.ID,
+		Provider: provider,
+		Subject:  identity.Subject,
+		Email:    identity.Email,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+var usernameInvalidChars = regexp.MustCompile(`[^a-z0-9_.]`)
+
+// revokeUnclaimedAccount replaces the password of an account nobody activated yet
+// and ends its sessions, before it is linked to a provider identity
+func (a *application) revokeUnclaimedAccount(ctx context.Context, userID int64) error {
+	hashedPassword, err := randomPasswordHash()
+	if err != nil {
+		return err
+	}
+
+	err = a.store.Users.UpdatePassword(ctx, userID, hashedPassword)
+	if err != nil {
+		return err
+	}
+
+	return a.store.RefreshTokens.RevokeAllUserTokens(ctx, userID)
+}
+
+// Accounts created from a provider get a random password nobody knows,
+// the owner can still set one through the password reset flow
+func (a *application) createOAuthUser(ctx context.Context, identity *oauth.Identity) (*store.User, error) {
+	suffix := make([]byte, 3)
+	if _, err := rand.Read(suffix); err != nil {
+		return nil, err
+	}
+
+	hashedPassword, err := randomPasswordHash()
+	if err != nil {
+		return nil, err
+	}
+
+	base := strings.ToLower(strings.Split(identity.Email, "@")[0])
+	base = usernameInvalidChars.ReplaceAllString(base, "")
+	if len(base) > 30 {
+		base = base[:30]
+	}
+
+	return a.store.Users.Create(ctx, &dto.CreateUserRequest{
+		Username: fmt.Sprintf("%s_%s", base, hex.EncodeToString(suffix)),
+		Email:    identity.Email,
+		Password: hashedPassword,
+	})
+}
+
+// randomPasswordHash hashes a password nobody knows
+func randomPasswordHash() (string, error) {
+	password := make([]byte, 32)
+	if _, err := rand.Read(password); err != nil {
+		return "", err
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(hex.EncodeToString(password)), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+
+	return string(hashedPassword), nil
+}
+
+func oauthStateKey(state string) string {
+	return fmt.Sprintf("oauth:state:%s", state)
+}
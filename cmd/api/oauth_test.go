@@ -0,0 +1,496 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sangtandoan/social/internal/config"
+	"github.com/sangtandoan/social/internal/models/dto"
+	"github.com/sangtandoan/social/internal/models/params"
+	"github.com/sangtandoan/social/internal/service/auth"
+	"github.com/sangtandoan/social/internal/service/oauth"
+	"github.com/sangtandoan/social/internal/store"
+	"github.com/sangtandoan/social/internal/utils"
+	"go.uber.org/zap"
+)
+
+// oauthTest runs the whole OAuth flow against the in-process FakeIssuer, with the
+// cache and the stores the flow touches kept in memory
+type oauthTest struct {
+	app        *application
+	handler    http.Handler
+	issuer     *oauth.FakeIssuer
+	cache      *memoryCache
+	users      *memoryUsers
+	identities *memoryIdentities
+	tokens     *memoryRefreshTokens
+}
+
+func newOAuthTest(t *testing.T) *oauthTest {
+	t.Helper()
+
+	gin.SetMode(gin.TestMode)
+	utils.Log = zap.NewNop().Sugar()
+
+	authConfig := &config.AuthConfig{
+		Secret:          strings.Repeat("s", 32),
+		Issuer:          "test",
+		AccessTokenTTL:  time.Minute,
+		RefreshTokenTTL: time.Hour,
+	}
+
+	issuer := oauth.NewFakeIssuer("http://localhost/oauth/fake")
+	h := &oauthTest{
+		issuer:     issuer,
+		cache:      &memoryCache{values: map[string]string{}},
+		users:      &memoryUsers{byID: map[int64]*store.User{}},
+		identities: &memoryIdentities{userIDs: map[string]int64{}},
+		tokens:     &memoryRefreshTokens{revokedUsers: map[int64]bool{}},
+	}
+
+	h.app = &application{
+		config: &config.Config{
+			AuthConfig: authConfig,
+			OAuthConfig: &config.OAuthConfig{
+				RedirectURL:  "http://localhost/api/v1/users/oauth",
+				FakeProvider: true,
+			},
+		},
+		store: &store.Store{
+			Users:         h.users,
+			Identities:    h.identities,
+			RefreshTokens: h.tokens,
+			Tx:            noTx{},
+		},
+		cache:      h.cache,
+		tokenMaker: auth.NewTokenMaker(authConfig),
+		oauthProviders: map[string]oauth.Provider{
+			"fake":  issuer.Provider("fake"),
+			"other": issuer.Provider("other"),
+		},
+		fakeIssuer: issuer,
+	}
+	h.handler = h.app.mount()
+
+	return h
+}
+
+// authorize starts the flow with the provider and lets the issuer sign the user in,
+// it returns the callback the issuer redirects to
+func (h *oauthTest) authorize(t *testing.T, provider, email string, verified bool) *url.URL {
+	t.Helper()
+
+	res := h.get(t, "/api/v1/users/oauth/"+provider+"/start?login_hint="+url.QueryEscape(email))
+	if res.Code != http.StatusOK {
+		t.Fatalf("start: status = %d, body = %s", res.Code, res.Body)
+	}
+
+	var body struct {
+		Data dto.OAuthStartResponse `json:"data"`
+	}
+	if err := json.Unmarshal(res.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+
+	authURL := body.Data.AuthorizationURL
+	if !verified {
+		authURL += "&email_verified=false"
+	}
+
+	authorized := httptest.NewRecorder()
+	h.issuer.ServeHTTP(authorized, httptest.NewRequest(http.MethodGet, authURL, nil))
+	if authorized.Code != http.StatusFound {
+		t.Fatalf("authorize: status = %d, body = %s", authorized.Code, authorized.Body)
+	}
+
+	callback, err := url.Parse(authorized.Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return callback
+}
+
+func (h *oauthTest) get(t *testing.T, target string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	res := httptest.NewRecorder()
+	h.handler.ServeHTTP(res, httptest.NewRequest(http.MethodGet, target, nil))
+	return res
+}
+
+func (h *oauthTest) callback(t *testing.T, callback *url.URL) *httptest.ResponseRecorder {
+	t.Helper()
+	return h.get(t, callback.RequestURI())
+}
+
+func TestOAuthLinksVerifiedEmail(t *testing.T) {
+	h := newOAuthTest(t)
+
+	existing, _ := h.users.Create(context.Background(), &dto.CreateUserRequest{
+		Username: "alice",
+		Email:    "alice@example.com",
+		Password: "registrant password",
+	})
+
+	res := h.callback(t, h.authorize(t, "fake", "alice@example.com", true))
+	if res.Code != http.StatusOK {
+		t.Fatalf("callback: status = %d, body = %s", res.Code, res.Body)
+	}
+
+	var body struct {
+		Data dto.LoginResponse `json:"data"`
+	}
+	if err := json.Unmarshal(res.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Data.AccessToken == "" || body.Data.RefreshToken == "" {
+		t.Fatalf("callback issued no tokens: %s", res.Body)
+	}
+
+	if userID := h.identities.userIDs["fake|fake|alice@example.com"]; userID != existing.ID {
+		t.Fatalf("identity linked to user %d, want %d", userID, existing.ID)
+	}
+	if !existing.Active {
+		t.Fatal("the account was not activated")
+	}
+
+	// Whoever registered the email before its owner signed in must be locked out
+	if existing.Password == "registrant password" {
+		t.Fatal("the password of the unclaimed account was kept")
+	}
+	if !h.tokens.revokedUsers[existing.ID] {
+		t.Fatal("the sessions of the unclaimed account were not revoked")
+	}
+
+	// The linked identity signs in to the same account
+	res = h.callback(t, h.authorize(t, "fake", "alice@example.com", true))
+	if res.Code != http.StatusOK {
+		t.Fatalf("second callback: status = %d, body = %s", res.Code, res.Body)
+	}
+	if len(h.users.byID) != 1 {
+		t.Fatalf("%d users, want the linked one only", len(h.users.byID))
+	}
+}
+
+func TestOAuthKeepsActiveAccountPassword(t *testing.T) {
+	h := newOAuthTest(t)
+
+	existing, _ := h.users.Create(context.Background(), &dto.CreateUserRequest{
+		Username: "alice",
+		Email:    "alice@example.com",
+		Password: "owner password",
+	})
+	existing.Active = true
+
+	res := h.callback(t, h.authorize(t, "fake", "alice@example.com", true))
+	if res.Code != http.StatusOK {
+		t.Fatalf("callback: status = %d, body = %s", res.Code, res.Body)
+	}
+
+	if existing.Password != "owner password" || h.tokens.revokedUsers[existing.ID] {
+		t.Fatal("linking changed the password or the sessions of an activated account")
+	}
+}
+
+func TestOAuthCreatesAccount(t *testing.T) {
+	h := newOAuthTest(t)
+
+	res := h.callback(t, h.authorize(t, "fake", "bob@example.com", true))
+	if res.Code != http.StatusOK {
+		t.Fatalf("callback: status = %d, body = %s", res.Code, res.Body)
+	}
+
+	userID, ok := h.identities.userIDs["fake|fake|bob@example.com"]
+	if !ok {
+		t.Fatal("identity was not linked")
+	}
+
+	user := h.users.byID[userID]
+	if user.Email != "bob@example.com" || !strings.HasPrefix(user.Username, "bob_") || !user.Active {
+		t.Fatalf("created user = %+v", user)
+	}
+}
+
+func TestOAuthRejectsUnverifiedEmail(t *testing.T) {
+	h := newOAuthTest(t)
+
+	h.users.Create(context.Background(), &dto.CreateUserRequest{
+		Username: "alice",
+		Email:    "alice@example.com",
+	})
+
+	res := h.callback(t, h.authorize(t, "fake", "alice@example.com", false))
+	if res.Code != utils.ErrOAuthEmailNotVerified.StatusCode {
+		t.Fatalf("status = %d, want %d", res.Code, utils.ErrOAuthEmailNotVerified.StatusCode)
+	}
+
+	if len(h.identities.userIDs) != 0 {
+		t.Fatalf("identities = %v, want none linked", h.identities.userIDs)
+	}
+}
+
+func TestOAuthChecksState(t *testing.T) {
+	h := newOAuthTest(t)
+
+	t.Run("unknown", func(t *testing.T) {
+		callback := h.authorize(t, "fake", "alice@example.com", true)
+
+		query := callback.Query()
+		query.Set("state", "forged")
+		callback.RawQuery = query.Encode()
+
+		if res := h.callback(t, callback); res.Code != http.StatusBadRequest {
+			t.Fatalf("status = %d, want %d", res.Code, http.StatusBadRequest)
+		}
+	})
+
+	t.Run("used twice", func(t *testing.T) {
+		callback := h.authorize(t, "fake", "alice@example.com", true)
+
+		if res := h.callback(t, callback); res.Code != http.StatusOK {
+			t.Fatalf("first use: status = %d, body = %s", res.Code, res.Body)
+		}
+		if res := h.callback(t, callback); res.Code != http.StatusBadRequest {
+			t.Fatalf("second use: status = %d, want %d", res.Code, http.StatusBadRequest)
+		}
+	})
+
+	t.Run("other provider", func(t *testing.T) {
+		callback := h.authorize(t, "fake", "alice@example.com", true)
+		callback.Path = strings.Replace(callback.Path, "/fake/", "/other/", 1)
+
+		if res := h.callback(t, callback); res.Code != http.StatusBadRequest {
+			t.Fatalf("status = %d, want %d", res.Code, http.StatusBadRequest)
+		}
+	})
+}
+
+func TestOAuthChecksCodeVerifier(t *testing.T) {
+	h := newOAuthTest(t)
+
+	callback := h.authorize(t, "fake", "alice@example.com", true)
+
+	// Swap the verifier kept with the state, the issuer must refuse the exchange
+	verifier, err := oauth.GenerateVerifier()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := json.Marshal(&oauthState{Provider: "fake", CodeVerifier: verifier})
+	h.cache.values[oauthStateKey(callback.Query().Get("state"))] = string(data)
+
+	res := h.callback(t, callback)
+	if res.Code != utils.ErrOAuthFailed.StatusCode {
+		t.Fatalf("status = %d, want %d", res.Code, utils.ErrOAuthFailed.StatusCode)
+	}
+
+	if len(h.identities.userIDs) != 0 {
+		t.Fatalf("identities = %v, want none linked", h.identities.userIDs)
+	}
+}
+
+var errCacheMiss = errors.New("cache miss")
+
+type memoryCache struct {
+	values map[string]string
+	mu     sync.Mutex
+}
+
+func (m *memoryCache) Get(ctx context.Context, key string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	value, ok := m.values[key]
+	if !ok {
+		return "", errCacheMiss
+	}
+	return value, nil
+}
+
+func (m *memoryCache) GetDel(ctx context.Context, key string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	value, ok := m.values[key]
+	if !ok {
+		return "", errCacheMiss
+	}
+	delete(m.values, key)
+	return value, nil
+}
+
+func (m *memoryCache) Set(ctx context.Context, key string, value any, expiration time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	switch v := value.(type) {
+	case []byte:
+		m.values[key] = string(v)
+	case string:
+		m.values[key] = v
+	default:
+		return errors.New("unsupported cache value")
+	}
+	return nil
+}
+
+func (m *memoryCache) SetNX(ctx context.Context, key string, value any, expiration time.Duration) error {
+	if _, err := m.Get(ctx, key); err == nil {
+		return nil
+	}
+	return m.Set(ctx, key, value, expiration)
+}
+
+func (m *memoryCache) Delete(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.values, key)
+	return nil
+}
+
+func (m *memoryCache) Incr(ctx context.Context, key string, window time.Duration) (int64, error) {
+	return 0, errors.New("not used by the oauth flow")
+}
+
+func (m *memoryCache) TTL(ctx context.Context, key string) (time.Duration, error) {
+	return 0, errors.New("not used by the oauth flow")
+}
+
+// memoryUsers keeps the users the oauth flow reads and writes, the methods it does
+// not use are left to the embedded store, which has no database
+type memoryUsers struct {
+	*store.UsersStore
+	byID map[int64]*store.User
+}
+
+func (m *memoryUsers) Create(ctx context.Context, arg *dto.CreateUserRequest) (*store.User, error) {
+	user := &store.User{
+		ID:        int64(len(m.byID) + 1),
+		Username:  arg.Username,
+		Email:     arg.Email,
+		Password:  arg.Password,
+		CreatedAt: time.Now(),
+	}
+	m.byID[user.ID] = user
+	return user, nil
+}
+
+func (m *memoryUsers) GetByID(ctx context.Context, id int64) (*store.User, error) {
+	user, ok := m.byID[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return user, nil
+}
+
+func (m *memoryUsers) GetByEmail(ctx context.Context, email string) (*store.User, error) {
+	for _, user := range m.byID {
+		if strings.EqualFold(user.Email, email) {
+			return user, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (m *memoryUsers) Activate(ctx context.Context, id int64) error {
+	user, err := m.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	user.Active = true
+	return nil
+}
+
+func (m *memoryUsers) UpdatePassword(ctx context.Context, id int64, password string) error {
+	user, err := m.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	user.Password = password
+	return nil
+}
+
+func (m *memoryUsers) ResetFailedLogins(ctx context.Context, id int64) error {
+	return nil
+}
+
+// memoryIdentities is keyed by provider|subject
+type memoryIdentities struct {
+	userIDs map[string]int64
+}
+
+func (m *memoryIdentities) GetUserID(ctx context.Context, provider, subject string) (int64, error) {
+	userID, ok := m.userIDs[provider+"|"+subject]
+	if !ok {
+		return -1, sql.ErrNoRows
+	}
+	return userID, nil
+}
+
+func (m *memoryIdentities) Create(ctx context.Context, arg *params.CreateIdentityParams) error {
+	m.userIDs[arg.Provider+"|"+arg.Subject] = arg.UserID
+	return nil
+}
+
+type memoryRefreshTokens struct {
+	revokedUsers map[int64]bool
+	tokens       []*params.CreateRefreshTokenParams
+}
+
+func (m *memoryRefreshTokens) Create(
+	ctx context.Context,
+	arg *params.CreateRefreshTokenParams,
+) (*store.RefreshToken, error) {
+	m.tokens = append(m.tokens, arg)
+	return &store.RefreshToken{
+		ID:        int64(len(m.tokens)),
+		UserID:    arg.UserID,
+		FamilyID:  arg.FamilyID,
+		ExpiresAt: arg.ExpiresAt,
+	}, nil
+}
+
+func (m *memoryRefreshTokens) GetByToken(ctx context.Context, token string) (*store.RefreshToken, error) {
+	return nil, sql.ErrNoRows
+}
+
+func (m *memoryRefreshTokens) Revoke(ctx context.Context, id int64) error {
+	return nil
+}
+
+func (m *memoryRefreshTokens) RevokeFamily(ctx context.Context, familyID string) error {
+	return nil
+}
+
+func (m *memoryRefreshTokens) RecordReuse(ctx context.Context, arg *params.RecordTokenReuseParams) error {
+	return nil
+}
+
+func (m *memoryRefreshTokens) ListSessions(ctx context.Context, userID int64) ([]*store.Session, error) {
+	return nil, nil
+}
+
+func (m *memoryRefreshTokens) RevokeSession(ctx context.Context, userID int64, familyID string) error {
+	return nil
+}
+
+func (m *memoryRefreshTokens) RevokeAllUserTokens(ctx context.Context, userID int64) error {
+	m.revokedUsers[userID] = true
+	return nil
+}
+
+type noTx struct{}
+
+func (noTx) WithTx(ctx context.Context, f func(txCtx context.Context) error) error {
+	return f(ctx)
+}
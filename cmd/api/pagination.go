@@ -0,0 +1,34 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/sangtandoan/social/internal/models/dto"
+	"github.com/sangtandoan/social/internal/utils"
+)
+
+const defaultPageLimit = 10
+
+// decodePage checks the page size and turns the cursor token back into a position
+func (a *application) decodePage(page *dto.Pagination) error {
+	err := utils.Validator.Struct(page)
+	if err != nil {
+		return utils.NewApiError(http.StatusBadRequest, "limit must be between 1 and 20")
+	}
+
+	if page.Cursor == "" {
+		return nil
+	}
+
+	page.After, err = utils.DecodeCursor([]byte(a.config.AuthConfig.CursorSecret), page.Cursor)
+	return err
+}
+
+// encodeCursor returns the token of the next page, empty on the last page
+func (a *application) encodeCursor(next *utils.Cursor) string {
+	if next == nil {
+		return ""
+	}
+
+	return utils.EncodeCursor([]byte(a.config.AuthConfig.CursorSecret), *next)
+}
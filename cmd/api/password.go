@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sangtandoan/social/internal/models/dto"
+	"github.com/sangtandoan/social/internal/models/params"
+	"github.com/sangtandoan/social/internal/service"
+	"github.com/sangtandoan/social/internal/utils"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const passwordResetExpiration = 15 * time.Minute
+
+// Always answers with the same message so the endpoint can not be used to find registered emails
+func (a *application) forgotPasswordHandler(c *gin.Context) {
+	var req dto.ForgotPasswordRequest
+
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		c.Error(utils.ErrInvalidJSON)
+		return
+	}
+
+	err = utils.Validator.Struct(&req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	res := utils.NewApiResponse("if the email exists, a reset link has been sent", nil)
+
+	user, err := a.store.Users.GetByEmail(c.Request.Context(), req.Email)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusOK, res)
+			return
+		}
+		c.Error(err)
+		return
+	}
+
+	token := uuid.New().String()
+
+	err = a.store.Tx.WithTx(c.Request.Context(), func(txCtx context.Context) error {
+		// Only the latest link stays valid
+		err := a.store.PasswordResets.DeleteByUserID(txCtx, user.ID)
+		if err != nil {
+			return err
+		}
+
+		return a.store.PasswordResets.CreatePasswordReset(txCtx, &params.CreatePasswordResetParams{
+			UserID:    user.ID,
+			Token:     utils.HashToken(token),
+			ExpiresAt: time.Now().Add(passwordResetExpiration),
+		})
+	})
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	emailReq := service.SendRequest{
+		To: []string{user.Email},
+		Data: &service.ResetPasswordData{
+			Username:  user.Username,
+			Token:     token,
+			ExpiresIn: passwordResetExpiration,
+		},
+		Temp: service.ResetPasswordTemplate,
+	}
+
+	// A mailer failure must not tell registered emails apart, nor make the caller wait
+	go func() {
+		if err := a.mailer.SendWithRetry(&emailReq, 3); err != nil {
+			utils.Log.Errorf("can not send password reset email to user %d: %v", user.ID, err)
+		}
+	}()
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (a *application) resetPasswordHandler(c *gin.Context) {
+	var req dto.ResetPasswordRequest
+
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		c.Error(utils.ErrInvalidJSON)
+		return
+	}
+
+	err = utils.Validator.Struct(&req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	err = a.store.Tx.WithTx(c.Request.Context(), func(txCtx context.Context) error {
+		userID, err := a.store.PasswordResets.ConsumePasswordReset(txCtx, utils.HashToken(req.Token))
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return utils.ErrInvalidToken
+			}
+			return err
+		}
+
+		err = a.store.Users.UpdatePassword(txCtx, userID, string(hashedPassword))
+		if err != nil {
+			return err
+		}
+
+		// Whoever knew the old password must not keep a session
+		return a.store.RefreshTokens.RevokeAllUserTokens(txCtx, userID)
+	})
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.NewApiResponse("reset password successfully", nil))
+}
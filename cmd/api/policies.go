@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sangtandoan/social/internal/middleware"
+	"github.com/sangtandoan/social/internal/models/dto"
+	"github.com/sangtandoan/social/internal/service/abac"
+	"github.com/sangtandoan/social/internal/store"
+	"github.com/sangtandoan/social/internal/utils"
+)
+
+func (a *application) getPoliciesHandler(c *gin.Context) {
+	policies, err := a.store.Policies.List(c.Request.Context())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.NewApiResponse("fetch policies successfully", policies))
+}
+
+func (a *application) createPolicyHandler(c *gin.Context) {
+	policy, ok := bindPolicy(c)
+	if !ok {
+		return
+	}
+
+	err := a.store.Policies.Create(c.Request.Context(), policy)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	a.abac.Invalidate()
+
+	c.JSON(http.StatusCreated, utils.NewApiResponse("created policy successfully", policy))
+}
+
+func (a *application) updatePolicyHandler(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.Error(utils.ErrNotFound)
+		return
+	}
+
+	policy, ok := bindPolicy(c)
+	if !ok {
+		return
+	}
+	policy.ID = id
+
+	err = a.store.Policies.Update(c.Request.Context(), policy)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	a.abac.Invalidate()
+
+	c.JSON(http.StatusOK, utils.NewApiResponse("updated policy successfully", policy))
+}
+
+func (a *application) deletePolicyHandler(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.Error(utils.ErrNotFound)
+		return
+	}
+
+	err = a.store.Policies.Delete(c.Request.Context(), id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	a.abac.Invalidate()
+
+	c.JSON(http.StatusOK, utils.NewApiResponse("deleted policy successfully", nil))
+}
+
+func bindPolicy(c *gin.Context) (*store.Policy, bool) {
+	var req dto.PolicyRequest
+
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		c.Error(utils.ErrInvalidJSON)
+		return nil, false
+	}
+
+	err = utils.Validator.Struct(&req)
+	if err != nil {
+		c.Error(err)
+		return nil, false
+	}
+
+	policy := &store.Policy{
+		Name:         req.Name,
+		Description:  req.Description,
+		Effect:       req.Effect,
+		ResourceType: req.ResourceType,
+		Actions:      req.Actions,
+		Conditions:   make([]store.PolicyCondition, 0, len(req.Conditions)),
+		Enabled:      req.Enabled == nil || *req.Enabled,
+	}
+	for _, condition := range req.Conditions {
+		policy.Conditions = append(policy.Conditions, store.PolicyCondition{
+			Attribute: condition.Attribute,
+			Operator:  condition.Operator,
+			Value:     condition.Value,
+			ValueFrom: condition.ValueFrom,
+		})
+	}
+
+	if err := abac.ValidatePolicy(policy); err != nil {
+		c.Error(utils.NewApiError(http.StatusBadRequest, err.Error()))
+		return nil, false
+	}
+
+	return policy, true
+}
+
+// authorize asks the policy engine whether the caller, anonymous or not,
+// may do the action. Resources the caller can't read are reported as not found.
+func (a *application) authorize(ctx context.Context, req *abac.Request) error {
+	req.SubjectID, _ = middleware.GetUserID(ctx)
+
+	decision, err := a.abac.Authorize(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	if !decision.Allowed() {
+		if req.Action == abac.ActionRead {
+			return utils.ErrNotFound
+		}
+		return utils.ErrForbidden
+	}
+
+	return nil
+}
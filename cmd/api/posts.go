@@ -1,39 +1,77 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"strconv"
 	"time"
+	"unicode/utf8"
 
 	"github.com/gin-gonic/gin"
+	"github.com/sangtandoan/social/internal/middleware"
 	"github.com/sangtandoan/social/internal/models/dto"
+	"github.com/sangtandoan/social/internal/service/abac"
 	"github.com/sangtandoan/social/internal/service/cache"
 	"github.com/sangtandoan/social/internal/store"
 	"github.com/sangtandoan/social/internal/utils"
 )
 
 type CreatePostPayload struct {
-	Title   string   `json:"title"`
-	Content string   `json:"content"`
-	Tags    []string `json:"tags"`
+	// PublishAt is required for scheduled posts
+	PublishAt *time.Time `json:"publish_at"`
+	Title     string     `json:"title"`
+	Content   string     `json:"content"`
+	// Status defaults to published
+	Status string `json:"status"`
+	// Visibility defaults to public
+	Visibility string   `json:"visibility"`
+	Tags       []string `json:"tags"`
 }
 
 func (app *application) createPostHandler(c *gin.Context) error {
+	userID, ok := middleware.GetUserID(c.Request.Context())
+	if !ok {
+		return utils.ErrUnauthorized
+	}
+
 	payload := &CreatePostPayload{}
 	if err := utils.ReadJSON(c, payload); err != nil {
 		return err
 	}
 
+	if payload.Status == "" {
+		payload.Status = store.PostStatusPublished
+	}
+
+	publishAt, err := resolvePublishAt(payload.Status, payload.PublishAt)
+	if err != nil {
+		return err
+	}
+
+	if err := validatePostText(&payload.Title, &payload.Content); err != nil {
+		return err
+	}
+
+	if payload.Visibility == "" {
+		payload.Visibility = store.PostVisibilityPublic
+	}
+	if err := validateVisibility(payload.Visibility); err != nil {
+		return err
+	}
+
 	post := &store.Post{
-		Title:   payload.Title,
-		Content: payload.Content,
-		Tags:    payload.Tags,
-		UserID:  1,
+		Title:      payload.Title,
+		Content:    payload.Content,
+		Tags:       payload.Tags,
+		UserID:     int(userID),
+		Status:     payload.Status,
+		PublishAt:  publishAt,
+		Visibility: payload.Visibility,
 	}
 
-	err := app.store.Posts.Create(c.Request.Context(), post)
+	err = app.store.Posts.Create(c.Request.Context(), post)
 	if err != nil {
 		return err
 	}
@@ -48,14 +86,13 @@ func (a *application) getPostHandler(c *gin.Context) error {
 		return err
 	}
 
-	cacheKey := fmt.Sprintf("user:%s", c.Param("id"))
-	lockKey := fmt.Sprintf("lock:%s", cacheKey)
+	cacheKey := postCacheKey(c.Param("id"))
+	lockKey := postLockKey(c.Param("id"))
 	cacheRespone, err := a.cache.Get(c.Request.Context(), cacheKey)
 	if err == nil {
 		var post store.Post
 		if err := json.Unmarshal([]byte(cacheRespone), &post); err == nil {
-			c.JSON(http.StatusOK, &post)
-			return nil
+			return a.writePost(c, &post)
 		}
 	}
 
@@ -76,30 +113,156 @@ func (a *application) getPostHandler(c *gin.Context) error {
 	cacheData, _ := json.Marshal(post)
 	a.cache.Set(c.Request.Context(), cacheKey, cacheData, cache.ExpirationTime)
 
+	return a.writePost(c, post)
+}
+
+// writePost answers with the post, or 304 when the client's copy is current.
+// The check comes first so a 304 does not tell who may not read the post that it exists.
+func (a *application) writePost(c *gin.Context, post *store.Post) error {
+	if err := a.authorizePostRead(c, post); err != nil {
+		return err
+	}
+
+	etag := postETag(post)
+	c.Header("ETag", etag)
+
+	if noneMatch(c.GetHeader("If-None-Match"), etag) {
+		c.Status(http.StatusNotModified)
+		return nil
+	}
+
 	c.JSON(http.StatusOK, post)
 	return nil
 }
 
+// resolvePublishAt checks the publish time that goes with a post status:
+// scheduled posts need one in the future, drafts have none and published posts go out now
+func resolvePublishAt(status string, publishAt *time.Time) (*time.Time, error) {
+	switch status {
+	case store.PostStatusDraft:
+		return nil, nil
+	case store.PostStatusPublished:
+		now := time.Now()
+		return &now, nil
+	case store.PostStatusScheduled:
+		if publishAt == nil || !publishAt.After(time.Now()) {
+			return nil, utils.NewApiError(
+				http.StatusBadRequest,
+				"scheduled posts need a publish_at in the future",
+			)
+		}
+		return publishAt, nil
+	}
+
+	return nil, utils.NewApiError(
+		http.StatusBadRequest,
+		"status must be one of draft, scheduled or published",
+	)
+}
+
+const (
+	maxPostTitleLength   = 300
+	maxPostContentLength = 20000
+)
+
+// validatePostText caps the title and content, nil ones are left unchanged.
+// Every revision keeps a copy and the revision diff is computed on the content.
+func validatePostText(title, content *string) error {
+	if title != nil && utf8.RuneCountInString(*title) > maxPostTitleLength {
+		return utils.NewApiError(
+			http.StatusBadRequest,
+			fmt.Sprintf("title can not be longer than %d characters", maxPostTitleLength),
+		)
+	}
+
+	if content != nil && utf8.RuneCountInString(*content) > maxPostContentLength {
+		return utils.NewApiError(
+			http.StatusBadRequest,
+			fmt.Sprintf("content can not be longer than %d characters", maxPostContentLength),
+		)
+	}
+
+	return nil
+}
+
+func validateVisibility(visibility string) error {
+	switch visibility {
+	case store.PostVisibilityPublic, store.PostVisibilityFollowers,
+		store.PostVisibilityMentioned, store.PostVisibilityPrivate:
+		return nil
+	}
+
+	return utils.NewApiError(
+		http.StatusBadRequest,
+		"visibility must be one of public, followers, mentioned or private",
+	)
+}
+
+// The cache holds the post whoever asked, so the check runs on every read.
+// Posts that are not published yet only exist for their author, and posts
+// outside the caller's audience look like missing ones.
+func (a *application) authorizePostRead(c *gin.Context, post *store.Post) error {
+	userID, _ := middleware.GetUserID(c.Request.Context())
+	isAuthor := userID != 0 && userID == int64(post.UserID)
+
+	if post.Status != store.PostStatusPublished && !isAuthor {
+		return utils.ErrNotFound
+	}
+
+	// Even a public post is hidden when its author's account is private, which the
+	// cached post does not tell, so the store decides for everyone but the author
+	if !isAuthor {
+		visible, err := a.store.Posts.IsVisibleTo(c.Request.Context(), int64(post.ID), userID)
+		if err != nil {
+			return err
+		}
+		if !visible {
+			return utils.ErrNotFound
+		}
+	}
+
+	return a.authorize(c.Request.Context(), &abac.Request{
+		Attributes:   abac.PostAttributes(post),
+		ResourceType: abac.ResourcePost,
+		Action:       abac.ActionRead,
+		ResourceID:   int64(post.ID),
+	})
+}
+
 func (a *application) getPostsHandler(c *gin.Context) error {
-	data, err := a.store.Posts.GetAll(c.Request.Context())
+	// Anonymous callers only see public posts
+	userID, _ := middleware.GetUserID(c.Request.Context())
+
+	page := dto.Pagination{Limit: defaultPageLimit}
+	if err := c.ShouldBindQuery(&page); err != nil {
+		return utils.NewApiError(http.StatusBadRequest, "invalid query")
+	}
+
+	if err := a.decodePage(&page); err != nil {
+		return err
+	}
+
+	data, next, err := a.store.Posts.GetAll(c.Request.Context(), userID, &page)
 	if err != nil {
 		return err
 	}
 
-	c.JSON(http.StatusOK, data)
+	c.JSON(
+		http.StatusOK,
+		utils.NewPageResponse("fetch posts successfully", data, a.encodeCursor(next)),
+	)
 	return nil
 }
 
 func (a *application) getUserFeedHandler(c *gin.Context) {
-	userID, ok := c.Request.Context().Value("userID").(int64)
+	userID, ok := middleware.GetUserID(c.Request.Context())
 	if !ok {
-		c.Error(fmt.Errorf("userID not found in context"))
+		c.Error(utils.ErrUnauthorized)
 		return
 	}
 
 	var req dto.UserFeedRequest
-	req.Offset = 0
-	req.Limit = 10
+	req.Limit = defaultPageLimit
 
 	err := c.ShouldBindQuery(&req)
 	if err != nil {
@@ -107,15 +270,24 @@ func (a *application) getUserFeedHandler(c *gin.Context) {
 		return
 	}
 
+	err = a.decodePage(&req.Pagination)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
 	req.ID = userID
 
-	res, err := a.store.Posts.GetUserFeed(c.Request.Context(), &req)
+	res, next, err := a.store.Posts.GetUserFeed(c.Request.Context(), &req)
 	if err != nil {
 		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, utils.NewApiResponse("Fetch feed successfully", res))
+	c.JSON(
+		http.StatusOK,
+		utils.NewPageResponse("Fetch feed successfully", res, a.encodeCursor(next)),
+	)
 }
 
 func (a *application) updatePostHandler(c *gin.Context) error {
@@ -133,11 +305,121 @@ func (a *application) updatePostHandler(c *gin.Context) error {
 		return err
 	}
 
-	post, err := a.store.Posts.UpdatePost(c.Request.Context(), &req)
+	userID, ok := middleware.GetUserID(c.Request.Context())
+	if !ok {
+		return utils.ErrUnauthorized
+	}
+	req.UserID = userID
+
+	req.Versions, err = parseIfMatch(c.GetHeader("If-Match"))
+	if err != nil {
+		return err
+	}
+
+	var post *store.Post
+	if req.Status != nil {
+		req.PublishAt, err = resolvePublishAt(*req.Status, req.PublishAt)
+		if err != nil {
+			return err
+		}
+	} else if req.PublishAt != nil {
+		return utils.NewApiError(http.StatusBadRequest, "publish_at can only be set with status")
+	}
+
+	if req.Visibility != nil {
+		if err := validateVisibility(*req.Visibility); err != nil {
+			return err
+		}
+	}
+
+	if err := validatePostText(req.Title, req.Content); err != nil {
+		return err
+	}
+
+	if req.IsEmpty() {
+		return utils.NewApiError(http.StatusBadRequest, "nothing to update")
+	}
+
+	err = a.store.Tx.WithTx(c.Request.Context(), func(txCtx context.Context) error {
+		err := a.store.PostRevisions.LockForEdit(txCtx, req.ID)
+		if err != nil {
+			return err
+		}
+
+		post, err = a.store.Posts.UpdatePost(txCtx, &req)
+		if err != nil {
+			return err
+		}
+
+		return a.store.PostRevisions.Create(txCtx, post, userID)
+	})
 	if err != nil {
 		return err
 	}
 
+	a.evictPost(c.Request.Context(), c.Param("id"))
+
+	c.Header("ETag", postETag(post))
 	c.JSON(http.StatusOK, post)
 	return nil
 }
+
+func (a *application) deletePostHandler(c *gin.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return utils.ErrNotFound
+	}
+
+	userID, ok := middleware.GetUserID(c.Request.Context())
+	if !ok {
+		return utils.ErrUnauthorized
+	}
+
+	err = a.store.Posts.Delete(c.Request.Context(), id, userID)
+	if err != nil {
+		return err
+	}
+
+	a.evictPost(c.Request.Context(), c.Param("id"))
+
+	c.JSON(http.StatusOK, utils.NewApiResponse("deleted post successfully", nil))
+	return nil
+}
+
+func (a *application) restorePostHandler(c *gin.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return utils.ErrNotFound
+	}
+
+	userID, ok := middleware.GetUserID(c.Request.Context())
+	if !ok {
+		return utils.ErrUnauthorized
+	}
+
+	err = a.store.Posts.Restore(c.Request.Context(), id, userID)
+	if err != nil {
+		return err
+	}
+
+	c.JSON(http.StatusOK, utils.NewApiResponse("restored post successfully", nil))
+	return nil
+}
+
+// evictPost drops the cached post and a rebuild lock that may be held on it,
+// so a reader waiting on the lock rebuilds from the database right away
+func (a *application) evictPost(ctx context.Context, id string) {
+	for _, key := range []string{postCacheKey(id), postLockKey(id)} {
+		if err := a.cache.Delete(ctx, key); err != nil {
+			utils.Log.Warnf("can not evict %s from cache: %v", key, err)
+		}
+	}
+}
+
+func postCacheKey(id string) string {
+	return fmt.Sprintf("user:%s", id)
+}
+
+func postLockKey(id string) string {
+	return fmt.Sprintf("lock:%s", postCacheKey(id))
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sangtandoan/social/internal/middleware"
+	"github.com/sangtandoan/social/internal/models/dto"
+	"github.com/sangtandoan/social/internal/store"
+	"github.com/sangtandoan/social/internal/utils"
+)
+
+type reactionResponse struct {
+	// Reaction is the caller's reaction after the toggle, nil when they have none
+	Reaction       *string              `json:"reaction"`
+	ReactionCounts store.ReactionCounts `json:"reaction_counts"`
+}
+
+func (a *application) togglePostReactionHandler(c *gin.Context) error {
+	post, err := a.getReadablePost(c)
+	if err != nil {
+		return err
+	}
+
+	res, err := a.toggleReaction(c, store.ReactionTargetPost, int64(post.ID))
+	if err != nil {
+		return err
+	}
+
+	// The cached copy of the post carries the counts
+	a.evictPost(c.Request.Context(), c.Param("id"))
+
+	c.JSON(http.StatusOK, utils.NewApiResponse("toggled reaction successfully", res))
+	return nil
+}
+
+func (a *application) toggleCommentReactionHandler(c *gin.Context) error {
+	post, err := a.getReadablePost(c)
+	if err != nil {
+		return err
+	}
+
+	commentID, err := strconv.ParseInt(c.Param("commentID"), 10, 64)
+	if err != nil {
+		return utils.ErrNotFound
+	}
+
+	comment, err := a.store.Comments.Get(c.Request.Context(), commentID, int64(post.ID))
+	if err != nil {
+		return err
+	}
+
+	res, err := a.toggleReaction(c, store.ReactionTargetComment, comment.ID)
+	if err != nil {
+		return err
+	}
+
+	c.JSON(http.StatusOK, utils.NewApiResponse("toggled reaction successfully", res))
+	return nil
+}
+
+func (a *application) toggleReaction(
+	c *gin.Context,
+	target store.ReactionTarget,
+	targetID int64,
+) (*reactionResponse, error) {
+	userID, ok := middleware.GetUserID(c.Request.Context())
+	if !ok {
+		return nil, utils.ErrUnauthorized
+	}
+
+	var req dto.ReactionRequest
+	if err := utils.ReadJSON(c, &req); err != nil {
+		return nil, utils.ErrInvalidJSON
+	}
+
+	if !store.IsReactionKind(req.Kind) {
+		return nil, utils.NewApiError(
+			http.StatusBadRequest,
+			fmt.Sprintf("kind must be one of %s", strings.Join(store.ReactionKinds, ", ")),
+		)
+	}
+
+	var res reactionResponse
+	err := a.store.Tx.WithTx(c.Request.Context(), func(txCtx context.Context) error {
+		reaction, counts, err := a.store.Reactions.Toggle(txCtx, target, targetID, userID, req.Kind)
+		if err != nil {
+			return err
+		}
+
+		if reaction != "" {
+			res.Reaction = &reaction
+		}
+		res.ReactionCounts = counts
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
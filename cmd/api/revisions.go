@@ -0,0 +1,139 @@
+package main
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sangtandoan/social/internal/middleware"
+	"github.com/sangtandoan/social/internal/models/dto"
+	"github.com/sangtandoan/social/internal/store"
+	"github.com/sangtandoan/social/internal/utils"
+)
+
+type revisionDiffResponse struct {
+	Title       []utils.DiffLine `json:"title"`
+	Content     []utils.DiffLine `json:"content"`
+	AddedTags   []string         `json:"added_tags"`
+	RemovedTags []string         `json:"removed_tags"`
+	From        int              `json:"from"`
+	To          int              `json:"to"`
+}
+
+func (a *application) getPostRevisionsHandler(c *gin.Context) error {
+	postID, err := a.getRevisionPostID(c)
+	if err != nil {
+		return err
+	}
+
+	revisions, err := a.store.PostRevisions.ListByPostID(c.Request.Context(), postID)
+	if err != nil {
+		return err
+	}
+
+	c.JSON(http.StatusOK, utils.NewApiResponse("fetch revisions successfully", revisions))
+	return nil
+}
+
+func (a *application) getPostRevisionHandler(c *gin.Context) error {
+	postID, err := a.getRevisionPostID(c)
+	if err != nil {
+		return err
+	}
+
+	rev, err := strconv.Atoi(c.Param("rev"))
+	if err != nil {
+		return utils.ErrNotFound
+	}
+
+	revision, err := a.store.PostRevisions.Get(c.Request.Context(), postID, rev)
+	if err != nil {
+		return err
+	}
+
+	c.JSON(http.StatusOK, utils.NewApiResponse("fetch revision successfully", revision))
+	return nil
+}
+
+func (a *application) getPostDiffHandler(c *gin.Context) error {
+	postID, err := a.getRevisionPostID(c)
+	if err != nil {
+		return err
+	}
+
+	var req dto.RevisionDiffRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		return utils.NewApiError(http.StatusBadRequest, "from and to must be revision numbers")
+	}
+
+	if err := utils.Validator.Struct(&req); err != nil {
+		return utils.NewApiError(http.StatusBadRequest, err.Error())
+	}
+
+	from, err := a.store.PostRevisions.Get(c.Request.Context(), postID, req.From)
+	if err != nil {
+		return err
+	}
+
+	to, err := a.store.PostRevisions.Get(c.Request.Context(), postID, req.To)
+	if err != nil {
+		return err
+	}
+
+	res := revisionDiffResponse{
+		Title:       utils.DiffLines(from.Title, to.Title),
+		Content:     utils.DiffLines(from.Content, to.Content),
+		AddedTags:   tagsMissingFrom(to.Tags, from.Tags),
+		RemovedTags: tagsMissingFrom(from.Tags, to.Tags),
+		From:        from.Revision,
+		To:          to.Revision,
+	}
+	c.JSON(http.StatusOK, utils.NewApiResponse("fetch diff successfully", res))
+	return nil
+}
+
+// getRevisionPostID returns the post id when the caller may see its history.
+// Authors may have edited things out on purpose, so only they and moderators can.
+func (a *application) getRevisionPostID(c *gin.Context) (int64, error) {
+	postID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return 0, utils.ErrNotFound
+	}
+
+	userID, ok := middleware.GetUserID(c.Request.Context())
+	if !ok {
+		return 0, utils.ErrUnauthorized
+	}
+
+	post, err := a.store.Posts.GetByID(c.Request.Context(), postID)
+	if err != nil {
+		return 0, err
+	}
+
+	if int64(post.UserID) == userID {
+		return postID, nil
+	}
+
+	allowed, err := a.hasPermission(c.Request.Context(), userID, store.PermPostUpdateAny)
+	if err != nil {
+		return 0, err
+	}
+
+	if !allowed {
+		return 0, utils.ErrNotFound
+	}
+
+	return postID, nil
+}
+
+func tagsMissingFrom(tags, other []string) []string {
+	res := []string{}
+	for _, tag := range tags {
+		if !slices.Contains(other, tag) {
+			res = append(res, tag)
+		}
+	}
+
+	return res
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sangtandoan/social/internal/middleware"
+	"github.com/sangtandoan/social/internal/models/dto"
+	"github.com/sangtandoan/social/internal/store"
+	"github.com/sangtandoan/social/internal/utils"
+)
+
+func (a *application) getPermissionsHandler(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c.Request.Context())
+	if !ok {
+		c.Error(utils.ErrUnauthorized)
+		return
+	}
+
+	permissions, err := a.store.Roles.GetUserPermissions(c.Request.Context(), userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.NewApiResponse("fetch permissions successfully", permissions))
+}
+
+func (a *application) assignRoleHandler(c *gin.Context) {
+	targetID, ok := a.getTargetUserID(c)
+	if !ok {
+		return
+	}
+
+	var req dto.AssignRoleRequest
+
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		c.Error(utils.ErrInvalidJSON)
+		return
+	}
+
+	err = utils.Validator.Struct(&req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	err = a.store.Roles.AssignRole(c.Request.Context(), targetID, req.Role)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.NewApiResponse("assigned role successfully", nil))
+}
+
+// Suspending signs the user out everywhere and disables their api keys,
+// access tokens already issued stay valid until they expire
+func (a *application) suspendUserHandler(c *gin.Context) {
+	targetID, ok := a.getTargetUserID(c)
+	if !ok {
+		return
+	}
+
+	err := a.store.Tx.WithTx(c.Request.Context(), func(txCtx context.Context) error {
+		err := a.store.Users.SetSuspended(txCtx, targetID, true)
+		if err != nil {
+			return err
+		}
+
+		return a.store.RefreshTokens.RevokeAllUserTokens(txCtx, targetID)
+	})
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.NewApiResponse("suspended user successfully", nil))
+}
+
+func (a *application) unsuspendUserHandler(c *gin.Context) {
+	targetID, ok := a.getTargetUserID(c)
+	if !ok {
+		return
+	}
+
+	err := a.store.Users.SetSuspended(c.Request.Context(), targetID, false)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.NewApiResponse("lifted suspension successfully", nil))
+}
+
+// getTargetUserID reads the :id of a moderation route. Nobody can moderate
+// their own account, so an admin can not lock themselves out by mistake.
+func (a *application) getTargetUserID(c *gin.Context) (int64, bool) {
+	targetID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.Error(utils.ErrNotFound)
+		return 0, false
+	}
+
+	userID, ok := middleware.GetUserID(c.Request.Context())
+	if !ok {
+		c.Error(utils.ErrUnauthorized)
+		return 0, false
+	}
+
+	if targetID == userID {
+		c.Error(utils.NewApiError(http.StatusBadRequest, "you can not moderate your own account"))
+		return 0, false
+	}
+
+	return targetID, true
+}
+
+func (a *application) hasPermission(
+	ctx context.Context,
+	userID int64,
+	permission store.Permission,
+) (bool, error) {
+	return a.store.Roles.CheckPermission(ctx, userID, permission)
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sangtandoan/social/internal/middleware"
+	"github.com/sangtandoan/social/internal/utils"
+)
+
+func (a *application) getSessionsHandler(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c.Request.Context())
+	if !ok {
+		c.Error(utils.ErrUnauthorized)
+		return
+	}
+
+	sessions, err := a.store.RefreshTokens.ListSessions(c.Request.Context(), userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	currentSessionID := middleware.GetSessionID(c.Request.Context())
+	for _, session := range sessions {
+		session.Current = session.ID == currentSessionID
+	}
+
+	c.JSON(http.StatusOK, utils.NewApiResponse("fetch sessions successfully", sessions))
+}
+
+func (a *application) revokeSessionHandler(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c.Request.Context())
+	if !ok {
+		c.Error(utils.ErrUnauthorized)
+		return
+	}
+
+	sessionID := c.Param("id")
+	if err := uuid.Validate(sessionID); err != nil {
+		c.Error(utils.ErrNotFound)
+		return
+	}
+
+	err := a.store.RefreshTokens.RevokeSession(c.Request.Context(), userID, sessionID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.NewApiResponse("revoke session successfully", nil))
+}
+
+// Signs the user out of every device, access tokens already issued stay valid until they expire
+func (a *application) revokeAllSessionsHandler(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c.Request.Context())
+	if !ok {
+		c.Error(utils.ErrUnauthorized)
+		return
+	}
+
+	err := a.store.RefreshTokens.RevokeAllUserTokens(c.Request.Context(), userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.NewApiResponse("revoke all sessions successfully", nil))
+}
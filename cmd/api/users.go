@@ -6,7 +6,10 @@ import (
 	"database/sql"
 	"encoding/hex"
 	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -14,10 +17,14 @@ import (
 	"github.com/sangtandoan/social/internal/models/dto"
 	"github.com/sangtandoan/social/internal/models/params"
 	"github.com/sangtandoan/social/internal/service"
+	"github.com/sangtandoan/social/internal/service/abac"
+	"github.com/sangtandoan/social/internal/store"
 	"github.com/sangtandoan/social/internal/utils"
 	"golang.org/x/crypto/bcrypt"
 )
 
+const invitationExpiration = 10 * time.Minute
+
 func (a *application) createUserHandler(c *gin.Context) {
 	var req dto.CreateUserRequest
 
@@ -54,17 +61,7 @@ func (a *application) createUserHandler(c *gin.Context) {
 			return err
 		}
 
-		token := uuid.New().String()
-		hash := sha256.Sum256([]byte(token))
-		hashedToken := hex.EncodeToString(hash[:])
-
-		params := params.CreateInvitationParams{
-			UserID:    user.ID,
-			Token:     hashedToken,
-			ExpiresAt: time.Now().Add(10 * time.Minute),
-		}
-
-		err = a.store.Invitations.CreateInvitation(txCtx, &params)
+		token, err := a.createInvitation(txCtx, user.ID)
 		if err != nil {
 			return err
 		}
@@ -101,10 +98,29 @@ func (a *application) createUserHandler(c *gin.Context) {
 	c.JSON(http.StatusCreated, utils.NewApiResponse("created user successfully", res))
 }
 
+func (a *application) createInvitation(ctx context.Context, userID int64) (string, error) {
+	token := uuid.New().String()
+	hash := sha256.Sum256([]byte(token))
+	hashedToken := hex.EncodeToString(hash[:])
+
+	params := params.CreateInvitationParams{
+		UserID:    userID,
+		Token:     hashedToken,
+		ExpiresAt: time.Now().Add(invitationExpiration),
+	}
+
+	err := a.store.Invitations.CreateInvitation(ctx, &params)
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
 func (a *application) activateUserHandler(c *gin.Context) {
 	token := c.Query("token")
 	if token == "" {
-		c.Error(utils.NewApiError(http.StatusNotFound, "activate token not found"))
+		c.Error(utils.ErrInvitationNotFound)
 		return
 	}
 
@@ -119,7 +135,7 @@ func (a *application) activateUserHandler(c *gin.Context) {
 			return err
 		}
 
-		return nil
+		return a.store.Invitations.MarkInvitationUsed(txCtx, token)
 	})
 	if err != nil {
 		c.Error(err)
@@ -129,6 +145,93 @@ func (a *application) activateUserHandler(c *gin.Context) {
 	c.JSON(http.StatusOK, utils.NewApiResponse("activate successfully", nil))
 }
 
+// Pending tokens are dropped before a new one is sent, so only the latest email works.
+// The answer is the same whether the email exists or not.
+func (a *application) resendActivationHandler(c *gin.Context) {
+	var req dto.ResendActivationRequest
+
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		c.Error(utils.ErrInvalidJSON)
+		return
+	}
+
+	err = utils.Validator.Struct(&req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	// The ip limiter alone does not stop flooding one inbox from many addresses
+	count, err := a.cache.Incr(
+		c.Request.Context(),
+		fmt.Sprintf("ratelimit:activate-resend:%s", strings.ToLower(req.Email)),
+		time.Hour,
+	)
+	if err == nil && count > 3 {
+		c.Error(utils.ErrTooManyRequests)
+		return
+	}
+
+	res := utils.NewApiResponse("if the account is waiting for activation, a new email has been sent", nil)
+
+	user, err := a.store.Users.GetByEmail(c.Request.Context(), req.Email)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusOK, res)
+			return
+		}
+		c.Error(err)
+		return
+	}
+
+	if user.Active {
+		c.JSON(http.StatusOK, res)
+		return
+	}
+
+	var token string
+	err = a.store.Tx.WithTx(c.Request.Context(), func(txCtx context.Context) error {
+		err := a.store.Invitations.DeleteUnusedByUserID(txCtx, user.ID)
+		if err != nil {
+			return err
+		}
+
+		token, err = a.createInvitation(txCtx, user.ID)
+		return err
+	})
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	emailReq := service.SendRequest{
+		To: []string{user.Email},
+		Data: &service.ConfirmData{
+			Username: user.Username,
+			Token:    token,
+		},
+		Temp: service.ConfirmTemplate,
+	}
+
+	// A mailer failure must not tell pending accounts apart, nor make the caller wait
+	go func() {
+		if err := a.mailer.SendWithRetry(&emailReq, 3); err != nil {
+			utils.Log.Errorf("can not resend activation email to user %d: %v", user.ID, err)
+		}
+	}()
+
+	c.JSON(http.StatusOK, res)
+}
+
+const (
+	maxFailedLogins      = 5
+	maxFailedLoginsPerIP = 20
+	failedLoginIPWindow  = 15 * time.Minute
+	baseLockDuration     = 5 * time.Minute
+	maxLockDuration      = 24 * time.Hour
+)
+
 func (a *application) loginHandler(c *gin.Context) {
 	var req dto.LoginRequest
 
@@ -138,19 +241,167 @@ func (a *application) loginHandler(c *gin.Context) {
 		return
 	}
 
+	ipKey := fmt.Sprintf("login-failed:%s", c.ClientIP())
+	if count, err := a.cache.Get(c.Request.Context(), ipKey); err == nil {
+		if n, _ := strconv.Atoi(count); n >= maxFailedLoginsPerIP {
+			c.Error(utils.ErrTooManyRequests)
+			return
+		}
+	}
+
 	user, err := a.store.Users.GetByEmail(c.Request.Context(), req.Email)
 	if err != nil {
+		a.cache.Incr(c.Request.Context(), ipKey, failedLoginIPWindow)
 		c.Error(utils.ErrNotFound)
 		return
 	}
 
+	if user.LockedUntil != nil && time.Now().Before(*user.LockedUntil) {
+		c.Header("Retry-After", strconv.Itoa(int(time.Until(*user.LockedUntil).Seconds())))
+		c.Error(utils.ErrAccountLocked)
+		return
+	}
+
 	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password))
 	if err != nil {
+		a.cache.Incr(c.Request.Context(), ipKey, failedLoginIPWindow)
+		if err := a.recordFailedLogin(c, user); err != nil {
+			c.Error(err)
+			return
+		}
 		c.Error(utils.ErrUnauthorized)
 		return
 	}
 
-	// TODO: return access and refresh tokens
+	// Checked after the password so the state of an account is not leaked to strangers
+	if !user.Active {
+		c.Error(utils.ErrAccountInactive)
+		return
+	}
+
+	if user.MFAEnabled {
+		a.mfaChallenge(c, user)
+		return
+	}
+
+	a.completeLogin(c, user)
+}
+
+func (a *application) completeLogin(c *gin.Context, user *store.User) {
+	if user.SuspendedAt != nil {
+		c.Error(utils.ErrAccountSuspended)
+		return
+	}
+
+	err := a.store.Users.ResetFailedLogins(c.Request.Context(), user.ID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	res, err := a.issueTokens(c.Request.Context(), newSession(c, user.ID))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, utils.NewApiResponse("login successfully", res))
+}
+
+// recordFailedLogin locks the account once it reaches maxFailedLogins wrong passwords.
+// Each new lock lasts twice as long as the previous one, up to maxLockDuration.
+func (a *application) recordFailedLogin(c *gin.Context, user *store.User) error {
+	attempts, err := a.store.Users.RecordFailedLogin(c.Request.Context(), user.ID)
+	if err != nil {
+		return err
+	}
+
+	if attempts.Failed < maxFailedLogins {
+		return nil
+	}
+
+	lockDuration := maxLockDuration
+	if attempts.LockCount < 10 {
+		lockDuration = min(baseLockDuration<<attempts.LockCount, maxLockDuration)
+	}
+	lockedUntil := time.Now().Add(lockDuration)
+
+	err = a.store.Users.Lock(c.Request.Context(), user.ID, lockedUntil)
+	if err != nil {
+		return err
+	}
+
+	emailReq := service.SendRequest{
+		To: []string{user.Email},
+		Data: &service.AccountLockedData{
+			Username:    user.Username,
+			IPAddress:   c.ClientIP(),
+			LockedUntil: lockedUntil,
+		},
+		Temp: service.AccountLockedTemplate,
+	}
+
+	// The caller is probably not the owner, do not make them wait for the email
+	go func() {
+		if err := a.mailer.SendWithRetry(&emailReq, 3); err != nil {
+			utils.Log.Errorf("can not send account locked email to user %d: %v", user.ID, err)
+		}
+	}()
+
+	return nil
+}
+
+func (a *application) getUserProfileHandler(c *gin.Context) {
+	user, ok := a.getReadableUser(c)
+	if !ok {
+		return
+	}
+
+	counts, err := a.store.Followers.Counts(c.Request.Context(), user.ID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	res := dto.UserProfileResponse{
+		ID:             user.ID,
+		Username:       user.Username,
+		CreatedAt:      user.CreatedAt,
+		FollowersCount: counts.Followers,
+		FollowingCount: counts.Following,
+		IsPrivate:      user.IsPrivate,
+	}
+	c.JSON(http.StatusOK, utils.NewApiResponse("fetch profile successfully", res))
+}
+
+// getReadableUser loads the user of a /users/:id route, accounts the caller
+// is not allowed to see look like missing ones
+func (a *application) getReadableUser(c *gin.Context) (*store.User, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.Error(utils.ErrNotFound)
+		return nil, false
+	}
+
+	user, err := a.store.Users.GetByID(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = utils.ErrNotFound
+		}
+		c.Error(err)
+		return nil, false
+	}
+
+	err = a.authorize(c.Request.Context(), &abac.Request{
+		Attributes:   abac.UserAttributes(user),
+		ResourceType: abac.ResourceUser,
+		Action:       abac.ActionRead,
+		ResourceID:   user.ID,
+	})
+	if err != nil {
+		c.Error(err)
+		return nil, false
+	}
 
-	c.JSON(http.StatusOK, utils.NewApiResponse("login successfully", nil))
+	return user, true
 }
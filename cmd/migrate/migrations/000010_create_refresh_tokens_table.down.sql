@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_refresh_tokens_user_id;
+DROP INDEX IF EXISTS idx_refresh_tokens_token;
+DROP TABLE IF EXISTS refresh_tokens;
@@ -0,0 +1,25 @@
+DROP INDEX IF EXISTS idx_refresh_tokens_family_id;
+
+ALTER TABLE refresh_tokens
+DROP COLUMN reused_user_agent;
+
+ALTER TABLE refresh_tokens
+DROP COLUMN reused_ip_address;
+
+ALTER TABLE refresh_tokens
+DROP COLUMN reused_at;
+
+ALTER TABLE refresh_tokens
+DROP COLUMN user_agent;
+
+ALTER TABLE refresh_tokens
+DROP COLUMN ip_address;
+
+ALTER TABLE refresh_tokens
+DROP COLUMN last_used_at;
+
+ALTER TABLE refresh_tokens
+DROP COLUMN is_revoked;
+
+ALTER TABLE refresh_tokens
+DROP COLUMN family_id;
@@ -0,0 +1,25 @@
+ALTER TABLE refresh_tokens
+ADD COLUMN family_id uuid NOT NULL DEFAULT gen_random_uuid();
+
+ALTER TABLE refresh_tokens
+ADD COLUMN is_revoked bool NOT NULL DEFAULT false;
+
+ALTER TABLE refresh_tokens
+ADD COLUMN last_used_at timestamp(0) with time zone;
+
+ALTER TABLE refresh_tokens
+ADD COLUMN ip_address varchar(45) NOT NULL DEFAULT '';
+
+ALTER TABLE refresh_tokens
+ADD COLUMN user_agent text NOT NULL DEFAULT '';
+
+ALTER TABLE refresh_tokens
+ADD COLUMN reused_at timestamp(0) with time zone;
+
+ALTER TABLE refresh_tokens
+ADD COLUMN reused_ip_address varchar(45);
+
+ALTER TABLE refresh_tokens
+ADD COLUMN reused_user_agent text;
+
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_family_id ON refresh_tokens (family_id);
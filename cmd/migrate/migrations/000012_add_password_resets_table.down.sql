@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_password_resets_user_id;
+DROP INDEX IF EXISTS idx_password_resets_token;
+DROP TABLE IF EXISTS password_resets;
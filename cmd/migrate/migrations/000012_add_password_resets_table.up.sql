@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS password_resets (
+    id bigserial PRIMARY KEY,
+    user_id bigint NOT NULL,
+    token bytea NOT NULL,
+    expires_at timestamp(0) with time zone NOT NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_password_resets_token ON password_resets (token);
+CREATE INDEX IF NOT EXISTS idx_password_resets_user_id ON password_resets (user_id);
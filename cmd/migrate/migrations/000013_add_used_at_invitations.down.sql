@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_invitations_user_id;
+
+ALTER TABLE invitations
+DROP COLUMN used_at;
+
+ALTER TABLE invitations
+ALTER COLUMN expires_at TYPE timestamp(0);
@@ -0,0 +1,7 @@
+ALTER TABLE invitations
+ALTER COLUMN expires_at TYPE timestamp(0) with time zone;
+
+ALTER TABLE invitations
+ADD COLUMN used_at timestamp(0) with time zone;
+
+CREATE INDEX IF NOT EXISTS idx_invitations_user_id ON invitations (user_id);
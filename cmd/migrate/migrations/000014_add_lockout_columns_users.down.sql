@@ -0,0 +1,8 @@
+ALTER TABLE users
+DROP COLUMN locked_until;
+
+ALTER TABLE users
+DROP COLUMN lock_count;
+
+ALTER TABLE users
+DROP COLUMN failed_login_attempts;
@@ -0,0 +1,8 @@
+ALTER TABLE users
+ADD COLUMN failed_login_attempts int NOT NULL DEFAULT 0;
+
+ALTER TABLE users
+ADD COLUMN lock_count int NOT NULL DEFAULT 0;
+
+ALTER TABLE users
+ADD COLUMN locked_until timestamp(0) with time zone;
@@ -0,0 +1,11 @@
+DROP INDEX IF EXISTS idx_mfa_recovery_codes_user_id;
+DROP TABLE IF EXISTS mfa_recovery_codes;
+
+ALTER TABLE users
+DROP COLUMN mfa_last_step;
+
+ALTER TABLE users
+DROP COLUMN mfa_enabled;
+
+ALTER TABLE users
+DROP COLUMN mfa_secret;
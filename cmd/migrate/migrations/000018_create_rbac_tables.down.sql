@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_users_role_id;
+
+ALTER TABLE users
+DROP COLUMN role_id;
+
+DROP TABLE IF EXISTS role_permissions;
+DROP TABLE IF EXISTS roles;
@@ -0,0 +1,42 @@
+CREATE TABLE IF NOT EXISTS roles (
+    id bigserial PRIMARY KEY,
+    name varchar(50) UNIQUE NOT NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS role_permissions (
+    role_id bigint NOT NULL,
+    permission varchar(100) NOT NULL,
+
+    PRIMARY KEY (role_id, permission),
+    FOREIGN KEY (role_id) REFERENCES roles (id) ON DELETE CASCADE
+);
+
+-- Users without a role are regular members and have no extra permission
+ALTER TABLE users
+ADD COLUMN role_id bigint REFERENCES roles (id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_users_role_id ON users (role_id);
+
+INSERT INTO roles (name) VALUES ('moderator'), ('admin') ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission)
+SELECT r.id, p.permission
+FROM roles r
+CROSS JOIN unnest(ARRAY[
+    'post:update:any',
+    'post:delete:any',
+    'comment:moderate'
+]) AS p(permission)
+WHERE r.name IN ('moderator', 'admin')
+ON CONFLICT DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission)
+SELECT r.id, p.permission
+FROM roles r
+CROSS JOIN unnest(ARRAY[
+    'user:suspend',
+    'role:assign'
+]) AS p(permission)
+WHERE r.name = 'admin'
+ON CONFLICT DO NOTHING;
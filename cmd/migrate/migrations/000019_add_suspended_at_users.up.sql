@@ -0,0 +1,2 @@
+ALTER TABLE users
+ADD COLUMN suspended_at timestamp(0) with time zone;
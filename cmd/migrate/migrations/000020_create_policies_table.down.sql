@@ -0,0 +1,4 @@
+DELETE FROM role_permissions WHERE permission = 'policy:manage';
+
+DROP INDEX IF EXISTS idx_policies_resource_type;
+DROP TABLE IF EXISTS policies;
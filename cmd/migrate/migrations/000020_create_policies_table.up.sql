@@ -0,0 +1,50 @@
+CREATE TABLE IF NOT EXISTS policies (
+    id bigserial PRIMARY KEY,
+    name varchar(100) UNIQUE NOT NULL,
+    description text NOT NULL DEFAULT '',
+    effect varchar(10) NOT NULL CHECK (effect IN ('allow', 'deny')),
+    resource_type varchar(50) NOT NULL,
+    actions text[] NOT NULL,
+    conditions jsonb NOT NULL DEFAULT '[]',
+    enabled boolean NOT NULL DEFAULT true,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    updated_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_policies_resource_type ON policies (resource_type) WHERE enabled;
+
+INSERT INTO role_permissions (role_id, permission)
+SELECT id, 'policy:manage' FROM roles WHERE name = 'admin'
+ON CONFLICT DO NOTHING;
+
+INSERT INTO policies (name, description, effect, resource_type, actions, conditions) VALUES
+(
+    'read-posts',
+    'Everyone can read posts',
+    'allow', 'post', '{read}', '[]'
+),
+(
+    'followers-only-posts',
+    'Posts tagged followers-only are only visible to the author and their followers',
+    'deny', 'post', '{read}',
+    '[
+        {"attribute": "resource.tags", "operator": "contains", "value": "followers-only"},
+        {"attribute": "relation.is_owner", "operator": "equals", "value": false},
+        {"attribute": "relation.follows_owner", "operator": "equals", "value": false}
+    ]'
+),
+(
+    'read-profiles',
+    'Everyone can read profiles',
+    'allow', 'user', '{read}', '[]'
+),
+(
+    'hide-suspended-profiles',
+    'Suspended profiles are only visible to moderators',
+    'deny', 'user', '{read}',
+    '[
+        {"attribute": "resource.suspended", "operator": "equals", "value": true},
+        {"attribute": "subject.role", "operator": "not_in", "value": ["moderator", "admin"]}
+    ]'
+)
+ON CONFLICT (name) DO NOTHING;
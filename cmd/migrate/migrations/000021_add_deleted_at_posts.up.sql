@@ -0,0 +1,5 @@
+ALTER TABLE posts
+ADD COLUMN deleted_at timestamp(0) with time zone;
+
+-- Only deleted posts are indexed, for the restore lookups and the purge job
+CREATE INDEX IF NOT EXISTS idx_posts_deleted_at ON posts (deleted_at) WHERE deleted_at IS NOT NULL;
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS post_revisions (
+    id bigserial PRIMARY KEY,
+    post_id bigint NOT NULL,
+    revision int NOT NULL,
+    title text NOT NULL,
+    content text NOT NULL,
+    tags varchar(100) [],
+    edited_by bigint,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+
+    UNIQUE (post_id, revision),
+    FOREIGN KEY (post_id) REFERENCES posts (id) ON DELETE CASCADE,
+    FOREIGN KEY (edited_by) REFERENCES users (id) ON DELETE SET NULL
+);
+
+ALTER TABLE posts
+ADD COLUMN edited_at timestamp(0) with time zone;
@@ -0,0 +1,12 @@
+ALTER TABLE posts
+ADD COLUMN version bigint NOT NULL DEFAULT 1;
+
+-- Revisions are numbered by version, carry on from the history recorded so far
+UPDATE posts p
+SET version = r.latest
+FROM (
+    SELECT post_id, MAX(revision) AS latest
+    FROM post_revisions
+    GROUP BY post_id
+) r
+WHERE r.post_id = p.id;
@@ -0,0 +1,11 @@
+ALTER TABLE posts
+ADD COLUMN status varchar(20) NOT NULL DEFAULT 'published'
+CHECK (status IN ('draft', 'scheduled', 'published'));
+
+-- When the post was or will be published, NULL for drafts
+ALTER TABLE posts
+ADD COLUMN publish_at timestamp(0) with time zone;
+
+UPDATE posts SET publish_at = created_at;
+
+CREATE INDEX IF NOT EXISTS idx_posts_scheduled ON posts (publish_at) WHERE status = 'scheduled';
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS post_mentions;
+
+ALTER TABLE posts
+DROP COLUMN visibility;
@@ -0,0 +1,13 @@
+ALTER TABLE posts
+ADD COLUMN visibility varchar(20) NOT NULL DEFAULT 'public'
+CHECK (visibility IN ('public', 'followers', 'mentioned', 'private'));
+
+-- Users @mentioned in a post, they can read it when its visibility is mentioned
+CREATE TABLE IF NOT EXISTS post_mentions (
+    post_id bigint NOT NULL,
+    user_id bigint NOT NULL,
+
+    PRIMARY KEY (post_id, user_id),
+    FOREIGN KEY (post_id) REFERENCES posts (id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
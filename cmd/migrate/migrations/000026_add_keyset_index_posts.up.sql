@@ -0,0 +1,3 @@
+-- Serves the (created_at, id) keyset pagination of post listings
+CREATE INDEX IF NOT EXISTS idx_posts_created_at_id ON posts (created_at DESC, id DESC)
+WHERE deleted_at IS NULL;
@@ -0,0 +1,8 @@
+-- Accounts a user follows, the primary key only serves the other direction
+CREATE INDEX IF NOT EXISTS idx_followers_follower_id ON followers (follower_id, user_id);
+
+-- Per author timeline, walked for every followed account in (created_at, id) order
+CREATE INDEX IF NOT EXISTS idx_posts_user_id_created_at_id ON posts (user_id, created_at DESC, id DESC)
+WHERE deleted_at IS NULL AND status = 'published';
+
+CREATE INDEX IF NOT EXISTS idx_posts_content ON posts USING gin (content gin_trgm_ops);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_comments_post_id_created_at_id;
+
+ALTER TABLE comments
+DROP COLUMN edited_at;
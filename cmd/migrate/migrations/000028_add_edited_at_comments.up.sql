@@ -0,0 +1,5 @@
+ALTER TABLE comments
+ADD COLUMN edited_at timestamp(0) with time zone;
+
+-- Serves the (created_at, id) keyset pagination of a post's comments
+CREATE INDEX IF NOT EXISTS idx_comments_post_id_created_at_id ON comments (post_id, created_at DESC, id DESC);
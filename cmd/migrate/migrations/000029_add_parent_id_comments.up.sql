@@ -0,0 +1,6 @@
+-- Replies point at the comment they answer, top level comments have no parent
+ALTER TABLE comments
+ADD COLUMN parent_id bigint REFERENCES comments (id) ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS idx_comments_parent_id ON comments (parent_id, created_at, id)
+WHERE parent_id IS NOT NULL;
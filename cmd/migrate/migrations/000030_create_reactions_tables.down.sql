@@ -0,0 +1,8 @@
+ALTER TABLE comments
+DROP COLUMN reaction_counts;
+
+ALTER TABLE posts
+DROP COLUMN reaction_counts;
+
+DROP TABLE IF EXISTS comment_reactions;
+DROP TABLE IF EXISTS post_reactions;
@@ -0,0 +1,28 @@
+CREATE TABLE IF NOT EXISTS post_reactions (
+    post_id bigint NOT NULL,
+    user_id bigint NOT NULL,
+    kind varchar(20) NOT NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+
+    PRIMARY KEY (post_id, user_id),
+    FOREIGN KEY (post_id) REFERENCES posts (id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS comment_reactions (
+    comment_id bigint NOT NULL,
+    user_id bigint NOT NULL,
+    kind varchar(20) NOT NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+
+    PRIMARY KEY (comment_id, user_id),
+    FOREIGN KEY (comment_id) REFERENCES comments (id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+-- Number of reactions of each kind, kept up to date with every toggle
+ALTER TABLE posts
+ADD COLUMN reaction_counts jsonb NOT NULL DEFAULT '{}';
+
+ALTER TABLE comments
+ADD COLUMN reaction_counts jsonb NOT NULL DEFAULT '{}';
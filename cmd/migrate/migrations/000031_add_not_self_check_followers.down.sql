@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_followers_user_id_created_at;
+
+ALTER TABLE followers
+DROP CONSTRAINT IF EXISTS followers_not_self;
@@ -0,0 +1,8 @@
+-- Self-follows inserted before the check would make it fail to apply
+DELETE FROM followers WHERE user_id = follower_id;
+
+ALTER TABLE followers
+ADD CONSTRAINT followers_not_self CHECK (user_id <> follower_id);
+
+-- Serves the (created_at, id) keyset pagination of follower listings
+CREATE INDEX IF NOT EXISTS idx_followers_user_id_created_at ON followers (user_id, created_at DESC, follower_id DESC);
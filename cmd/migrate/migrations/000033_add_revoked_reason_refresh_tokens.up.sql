@@ -0,0 +1,9 @@
+-- Why a token was revoked, only presenting a rotated one again means it leaked
+ALTER TABLE refresh_tokens
+ADD COLUMN revoked_reason varchar(20)
+CHECK (revoked_reason IN ('rotated', 'reused', 'signed_out'));
+
+-- Rotation is the only revocation that records when the token was used
+UPDATE refresh_tokens
+SET revoked_reason = CASE WHEN last_used_at IS NOT NULL THEN 'rotated' ELSE 'signed_out' END
+WHERE is_revoked;
@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_posts_user_id_publish_at_id;
+DROP INDEX IF EXISTS idx_posts_publish_at_id;
+
+CREATE INDEX IF NOT EXISTS idx_posts_created_at_id ON posts (created_at DESC, id DESC)
+WHERE deleted_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_posts_user_id_created_at_id ON posts (user_id, created_at DESC, id DESC)
+WHERE deleted_at IS NULL AND status = 'published';
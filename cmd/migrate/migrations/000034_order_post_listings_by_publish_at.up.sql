@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS idx_posts_created_at_id;
+DROP INDEX IF EXISTS idx_posts_user_id_created_at_id;
+
+-- Post listings page on (publish_at, id), a post is placed by when it went out
+CREATE INDEX IF NOT EXISTS idx_posts_publish_at_id ON posts (publish_at DESC, id DESC)
+WHERE deleted_at IS NULL AND status = 'published';
+
+-- Per author timeline, walked for every followed account in (publish_at, id) order
+CREATE INDEX IF NOT EXISTS idx_posts_user_id_publish_at_id ON posts (user_id, publish_at DESC, id DESC)
+WHERE deleted_at IS NULL AND status = 'published';
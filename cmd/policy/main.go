@@ -0,0 +1,124 @@
+// Command policy checks rule files before they are deployed.
+//
+//	policy check -file policy.conf
+//	policy test -file policy.conf -resource post -action read -attr role=user -attr resource_tags='["followers-only"]'
+//
+// Attributes use the names of the rule file, without the r. prefix. Values are
+// read as JSON when they parse as JSON, as plain strings otherwise. test exits
+// with 0 when the request is allowed and 1 when it is denied.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/sangtandoan/social/internal/service/abac"
+	"github.com/sangtandoan/social/internal/utils"
+	"go.uber.org/zap"
+)
+
+type attrFlags map[string]any
+
+func (a attrFlags) String() string {
+	return fmt.Sprint(map[string]any(a))
+}
+
+func (a attrFlags) Set(s string) error {
+	key, raw, found := strings.Cut(s, "=")
+	if !found || key == "" {
+		return fmt.Errorf("expected name=value, got %q", s)
+	}
+
+	var value any
+	if err := json.Unmarshal([]byte(raw), &value); err != nil {
+		value = raw
+	}
+	a[strings.TrimPrefix(key, "r.")] = value
+
+	return nil
+}
+
+func main() {
+	utils.Log = zap.Must(zap.NewDevelopment()).Sugar()
+	defer utils.Log.Sync()
+
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	switch os.Args[1] {
+	case "check":
+		check(os.Args[2:])
+	case "test":
+		test(os.Args[2:])
+	default:
+		usage()
+	}
+}
+
+func check(args []string) {
+	fs := flag.NewFlagSet("check", flag.ExitOnError)
+	file := fs.String("file", "policy.conf", "rule file to check")
+	fs.Parse(args)
+
+	rules := load(*file)
+	fmt.Printf("%s: %d rules ok\n", *file, rules.Len())
+}
+
+func test(args []string) {
+	attrs := attrFlags{}
+
+	fs := flag.NewFlagSet("test", flag.ExitOnError)
+	file := fs.String("file", "policy.conf", "rule file to evaluate")
+	resource := fs.String("resource", "", "resource type of the request, e.g. post")
+	action := fs.String("action", "", "action of the request, e.g. read")
+	fs.Var(attrs, "attr", "request attribute as name=value, can be repeated")
+	fs.Parse(args)
+
+	if *action == "" {
+		fmt.Fprintln(os.Stderr, "-action is required")
+		os.Exit(2)
+	}
+
+	attrs["action"] = *action
+	if *resource != "" {
+		attrs["resource_type"] = *resource
+	}
+
+	decision := load(*file).Evaluate(attrs)
+	switch decision.Effect {
+	case abac.EffectAllow:
+		fmt.Printf("allow by %s\n", decision.Policy)
+	case abac.EffectDeny:
+		fmt.Printf("deny by %s\n", decision.Policy)
+		os.Exit(1)
+	default:
+		fmt.Println("deny, no rule matches")
+		os.Exit(1)
+	}
+}
+
+func load(path string) *abac.RuleSet {
+	file, err := os.Open(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	defer file.Close()
+
+	rules, err := abac.ParseRules(file)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+		os.Exit(2)
+	}
+
+	return rules
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: policy check|test -file policy.conf [flags]")
+	os.Exit(2)
+}
@@ -37,15 +37,41 @@ type MailerConfig struct {
 	Port       int    `mapstructure:"MAIL_PORT"`
 }
 
+type AuthConfig struct {
+	Secret          string        `mapstructure:"JWT_SECRET"`
+	Issuer          string        `mapstructure:"JWT_ISSUER"`
+	AccessTokenTTL  time.Duration `mapstructure:"JWT_ACCESS_TTL"`
+	RefreshTokenTTL time.Duration `mapstructure:"JWT_REFRESH_TTL"`
+	// Signs pagination cursors, defaults to the JWT secret
+	CursorSecret string `mapstructure:"CURSOR_SECRET"`
+}
+
+type OAuthConfig struct {
+	// Callback base, the provider name and /callback are appended
+	RedirectURL        string `mapstructure:"OAUTH_REDIRECT_URL"`
+	GoogleClientID     string `mapstructure:"OAUTH_GOOGLE_CLIENT_ID"`
+	GoogleClientSecret string `mapstructure:"OAUTH_GOOGLE_CLIENT_SECRET"`
+	// Serves an in-process fake provider, only for development and tests
+	FakeProvider bool `mapstructure:"OAUTH_FAKE_PROVIDER"`
+}
+
 type Config struct {
 	DbConfig     *dbConfig
 	MailerConfig *MailerConfig
 	CacheConfig  *RedisConfig
+	AuthConfig   *AuthConfig
+	OAuthConfig  *OAuthConfig
 	Addr         string `mapstructure:"ADDR"`
+	// Optional rule file evaluated next to the stored policies, reloaded on change
+	PolicyFile string `mapstructure:"POLICY_FILE"`
 }
 
 var cfg Config
 
+// minSecretLength is the shortest secret accepted to sign tokens and cursors,
+// HS256 needs a key at least as long as its 32 byte hash
+const minSecretLength = 32
+
 func LoadCfg() *Config {
 	viper.SetConfigFile(".env")
 
@@ -78,6 +104,34 @@ func LoadCfg() *Config {
 		log.Fatal("can not unmarshal cfg file")
 	}
 
+	var authConfig AuthConfig
+	err = viper.Unmarshal(&authConfig)
+	if err != nil {
+		log.Fatal("can not unmarshal cfg file")
+	}
+
+	if len(authConfig.Secret) < minSecretLength {
+		log.Fatalf("JWT_SECRET must be at least %d bytes long", minSecretLength)
+	}
+
+	if authConfig.AccessTokenTTL == 0 {
+		authConfig.AccessTokenTTL = 15 * time.Minute
+	}
+	if authConfig.RefreshTokenTTL == 0 {
+		authConfig.RefreshTokenTTL = 30 * 24 * time.Hour
+	}
+	if authConfig.CursorSecret == "" {
+		authConfig.CursorSecret = authConfig.Secret
+	} else if len(authConfig.CursorSecret) < minSecretLength {
+		log.Fatalf("CURSOR_SECRET must be at least %d bytes long", minSecretLength)
+	}
+
+	var oauthConfig OAuthConfig
+	err = viper.Unmarshal(&oauthConfig)
+	if err != nil {
+		log.Fatal("can not unmarshal cfg file")
+	}
+
 	dbConfig.Addr = fmt.Sprintf(
 		"postgres://%s:%s@localhost:5432/social?sslmode=disable",
 		dbConfig.User,
@@ -87,6 +141,8 @@ func LoadCfg() *Config {
 	cfg.DbConfig = &dbConfig
 	cfg.MailerConfig = &mailerConfig
 	cfg.CacheConfig = &redisConfig
+	cfg.AuthConfig = &authConfig
+	cfg.OAuthConfig = &oauthConfig
 
 	return &cfg
 }
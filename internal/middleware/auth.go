@@ -0,0 +1,122 @@
+package middleware
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sangtandoan/social/internal/service/auth"
+	"github.com/sangtandoan/social/internal/store"
+	"github.com/sangtandoan/social/internal/utils"
+)
+
+type (
+	UserIDKey    struct{}
+	SessionIDKey struct{}
+	ScopesKey    struct{}
+)
+
+type APIKeyStore interface {
+	GetByHash(ctx context.Context, keyHash string) (*store.APIKey, error)
+	Touch(ctx context.Context, id int64) error
+}
+
+// Authenticate accepts either an access token from a login or a personal api key
+// as the bearer credential and stores the user id in the request context
+func Authenticate(tokenMaker *auth.TokenMaker, apiKeys APIKeyStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader("Authorization")
+
+		tokenString, found := strings.CutPrefix(header, "Bearer ")
+		if !found || tokenString == "" {
+			c.Error(utils.ErrUnauthorized)
+			c.Abort()
+			return
+		}
+
+		if auth.IsAPIKey(tokenString) {
+			key, err := apiKeys.GetByHash(c.Request.Context(), utils.HashToken(tokenString))
+			if err != nil {
+				c.Error(err)
+				c.Abort()
+				return
+			}
+
+			if err := apiKeys.Touch(c.Request.Context(), key.ID); err != nil {
+				utils.Log.Warnf("can not record usage of api key %d: %v", key.ID, err)
+			}
+
+			ctx := context.WithValue(c.Request.Context(), UserIDKey{}, key.UserID)
+			ctx = context.WithValue(ctx, ScopesKey{}, key.Scopes)
+			c.Request = c.Request.WithContext(ctx)
+
+			c.Next()
+			return
+		}
+
+		claims, err := tokenMaker.VerifyAccessToken(tokenString)
+		if err != nil {
+			c.Error(utils.ErrInvalidToken)
+			c.Abort()
+			return
+		}
+
+		ctx := context.WithValue(c.Request.Context(), UserIDKey{}, claims.UserID)
+		ctx = context.WithValue(ctx, SessionIDKey{}, claims.SessionID)
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+	}
+}
+
+// OptionalAuthenticate lets anonymous requests through, but a credential that is sent must be valid
+func OptionalAuthenticate(tokenMaker *auth.TokenMaker, apiKeys APIKeyStore) gin.HandlerFunc {
+	authenticate := Authenticate(tokenMaker, apiKeys)
+
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "" {
+			c.Next()
+			return
+		}
+
+		authenticate(c)
+	}
+}
+
+// RequireScope lets logged in users through, api keys need the scope
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		scopes, isAPIKey := c.Request.Context().Value(ScopesKey{}).([]string)
+		if isAPIKey && !slices.Contains(scopes, scope) {
+			c.Error(utils.ErrInsufficientScope)
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// RequireSession rejects api keys, for account management that only the owner can do
+func RequireSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, isAPIKey := c.Request.Context().Value(ScopesKey{}).([]string); isAPIKey {
+			c.Error(utils.ErrInsufficientScope)
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func GetUserID(ctx context.Context) (int64, bool) {
+	userID, ok := ctx.Value(UserIDKey{}).(int64)
+	return userID, ok
+}
+
+func GetSessionID(ctx context.Context) string {
+	sessionID, _ := ctx.Value(SessionIDKey{}).(string)
+	return sessionID
+}
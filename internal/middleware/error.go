@@ -36,8 +36,23 @@ func GlobalErrorHandler() gin.HandlerFunc {
 }
 
 func handlePostgresError(err *pq.Error, fallback *utils.ApiError) {
-	if err.Code == "23505" {
+	switch {
+	case err.Code == "23505" && err.Table == "followers":
+		fallback.StatusCode = http.StatusConflict
+		fallback.Msg = gin.H{"msg": "you are already following this user"}
+	case err.Code == "23505" && err.Table == "follow_requests":
+		fallback.StatusCode = http.StatusConflict
+		fallback.Msg = gin.H{"msg": "you have already asked to follow this user"}
+	case err.Code == "23505":
 		fallback.StatusCode = http.StatusBadRequest
 		fallback.Msg = gin.H{"msg": "username or email has existed"}
+	case err.Code == "23514" &&
+		(err.Constraint == "followers_not_self" || err.Constraint == "follow_requests_not_self"):
+		fallback.StatusCode = http.StatusBadRequest
+		fallback.Msg = gin.H{"msg": "you can not follow yourself"}
+	case err.Code == "23503" && (err.Table == "followers" || err.Table == "follow_requests"):
+		// The account to follow does not exist
+		fallback.StatusCode = http.StatusNotFound
+		fallback.Msg = gin.H{"msg": "resource not found"}
 	}
 }
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sangtandoan/social/internal/store"
+	"github.com/sangtandoan/social/internal/utils"
+)
+
+type PermissionChecker interface {
+	CheckPermission(ctx context.Context, userID int64, permission store.Permission) (bool, error)
+}
+
+// RequirePermission only lets through users whose role grants the permission,
+// it must run after Authenticate
+func RequirePermission(checker PermissionChecker, permission store.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, ok := GetUserID(c.Request.Context())
+		if !ok {
+			c.Error(utils.ErrUnauthorized)
+			c.Abort()
+			return
+		}
+
+		allowed, err := checker.CheckPermission(c.Request.Context(), userID, permission)
+		if err != nil {
+			c.Error(err)
+			c.Abort()
+			return
+		}
+
+		if !allowed {
+			c.Error(utils.ErrForbidden)
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
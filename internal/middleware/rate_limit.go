@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sangtandoan/social/internal/service/cache"
+	"github.com/sangtandoan/social/internal/utils"
+)
+
+// RateLimit allows limit requests per client ip in every window.
+// When redis is unavailable the request goes through instead of locking everyone out.
+func RateLimit(cache cache.Cache, name string, limit int64, window time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := fmt.Sprintf("ratelimit:%s:%s", name, c.ClientIP())
+
+		count, err := cache.Incr(c.Request.Context(), key, window)
+		if err != nil {
+			utils.Log.Warnf("rate limiter is unavailable: %v", err)
+			c.Next()
+			return
+		}
+
+		if count > limit {
+			if ttl, err := cache.TTL(c.Request.Context(), key); err == nil && ttl > 0 {
+				c.Header("Retry-After", strconv.Itoa(int(ttl.Seconds())))
+			}
+
+			c.Error(utils.ErrTooManyRequests)
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
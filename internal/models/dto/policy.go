@@ -0,0 +1,18 @@
+package dto
+
+type PolicyCondition struct {
+	Value     any    `json:"value"`
+	Attribute string `json:"attribute,omitempty"  validate:"required"`
+	Operator  string `json:"operator,omitempty"   validate:"required"`
+	ValueFrom string `json:"value_from,omitempty"`
+}
+
+type PolicyRequest struct {
+	Enabled      *bool             `json:"enabled,omitempty"`
+	Name         string            `json:"name,omitempty"          validate:"required,max=100"`
+	Description  string            `json:"description,omitempty"`
+	Effect       string            `json:"effect,omitempty"        validate:"required,oneof=allow deny"`
+	ResourceType string            `json:"resource_type,omitempty" validate:"required,max=50"`
+	Actions      []string          `json:"actions,omitempty"       validate:"required,min=1,dive,required"`
+	Conditions   []PolicyCondition `json:"conditions,omitempty"    validate:"dive"`
+}
@@ -1,8 +1,12 @@
 package dto
 
+import "github.com/sangtandoan/social/internal/utils"
+
 type Pagination struct {
-	Offset int `form:"offset" validate:"min=0"`
-	Limit  int `form:"limit"  validate:"min=1,max=20"`
+	// After is the position decoded from Cursor, nil for the first page
+	After  *utils.Cursor `form:"-"`
+	Cursor string        `form:"cursor"`
+	Limit  int           `form:"limit"  validate:"min=1,max=20"`
 }
 
 type UserFeedRequest struct {
@@ -11,3 +15,23 @@ type UserFeedRequest struct {
 	Pagination `          form:"pagination"`
 	ID         int64 `form:"-"`
 }
+
+type RevisionDiffRequest struct {
+	From int `form:"from" validate:"required,min=1"`
+	To   int `form:"to"   validate:"required,min=1"`
+}
+
+type CommentRequest struct {
+	// ParentID makes the comment a reply, it is ignored when editing
+	ParentID *int64 `json:"parent_id"`
+	Content  string `json:"content"   validate:"required,max=2000"`
+}
+
+// ThreadRequest is how many levels of replies to load below each comment
+type ThreadRequest struct {
+	Depth int `form:"depth" validate:"min=0,max=5"`
+}
+
+type ReactionRequest struct {
+	Kind string `json:"kind" validate:"required"`
+}
@@ -1,5 +1,7 @@
 package dto
 
+import "time"
+
 type CreateUserRequest struct {
 	Username string `json:"username,omitempty" validate:"required,min=3,max=50"`
 	Email    string `json:"email,omitempty"    validate:"required,email"`
@@ -18,7 +20,89 @@ type ActivateUserRequest struct {
 	UserID int64
 }
 
+type ResendActivationRequest struct {
+	Email string `json:"email,omitempty" validate:"required,email"`
+}
+
 type LoginRequest struct {
 	Email    string `json:"email,omitempty"`
 	Password string `json:"password,omitempty"`
 }
+
+type LoginResponse struct {
+	AccessTokenExpiresAt  time.Time `json:"access_token_expires_at"`
+	RefreshTokenExpiresAt time.Time `json:"refresh_token_expires_at"`
+	AccessToken           string    `json:"access_token"`
+	RefreshToken          string    `json:"refresh_token"`
+}
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token,omitempty" validate:"required"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email,omitempty" validate:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token,omitempty"    validate:"required"`
+	Password string `json:"password,omitempty" validate:"required,min=3,max=20"`
+}
+
+type MFAChallengeResponse struct {
+	ExpiresAt   time.Time `json:"expires_at"`
+	MFAToken    string    `json:"mfa_token"`
+	MFARequired bool      `json:"mfa_required"`
+}
+
+type MFALoginRequest struct {
+	MFAToken     string `json:"mfa_token,omitempty"     validate:"required"`
+	Code         string `json:"code,omitempty"          validate:"required_without=RecoveryCode"`
+	RecoveryCode string `json:"recovery_code,omitempty" validate:"required_without=Code"`
+}
+
+type MFAEnrollResponse struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+}
+
+type MFAConfirmRequest struct {
+	Code string `json:"code,omitempty" validate:"required,len=6,numeric"`
+}
+
+type MFAConfirmResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type MFADisableRequest struct {
+	Password     string `json:"password,omitempty"      validate:"required"`
+	Code         string `json:"code,omitempty"          validate:"required_without=RecoveryCode"`
+	RecoveryCode string `json:"recovery_code,omitempty" validate:"required_without=Code"`
+}
+
+type OAuthStartResponse struct {
+	AuthorizationURL string `json:"authorization_url"`
+}
+
+type CreateAPIKeyRequest struct {
+	Name          string   `json:"name,omitempty"            validate:"required,max=100"`
+	Scopes        []string `json:"scopes,omitempty"          validate:"required,min=1,dive,required"`
+	ExpiresInDays int      `json:"expires_in_days,omitempty" validate:"min=0,max=365"`
+}
+
+type AssignRoleRequest struct {
+	Role string `json:"role,omitempty" validate:"required,max=50"`
+}
+
+type UserProfileResponse struct {
+	CreatedAt      time.Time `json:"created_at"`
+	Username       string    `json:"username"`
+	ID             int64     `json:"id"`
+	FollowersCount int64     `json:"followers_count"`
+	FollowingCount int64     `json:"following_count"`
+	IsPrivate      bool      `json:"is_private"`
+}
+
+type PrivacyRequest struct {
+	Private *bool `json:"private" validate:"required"`
+}
@@ -0,0 +1,12 @@
+package params
+
+import "time"
+
+type CreateAPIKeyParams struct {
+	ExpiresAt *time.Time
+	Name      string
+	Prefix    string
+	KeyHash   string
+	Scopes    []string
+	UserID    int64
+}
@@ -0,0 +1,8 @@
+package params
+
+type CreateIdentityParams struct {
+	Provider string
+	Subject  string
+	Email    string
+	UserID   int64
+}
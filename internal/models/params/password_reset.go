@@ -0,0 +1,9 @@
+package params
+
+import "time"
+
+type CreatePasswordResetParams struct {
+	Token     string
+	UserID    int64
+	ExpiresAt time.Time
+}
@@ -0,0 +1,18 @@
+package params
+
+import "time"
+
+type CreateRefreshTokenParams struct {
+	ExpiresAt time.Time
+	Token     string
+	FamilyID  string
+	IPAddress string
+	UserAgent string
+	UserID    int64
+}
+
+type RecordTokenReuseParams struct {
+	IPAddress string
+	UserAgent string
+	ID        int64
+}
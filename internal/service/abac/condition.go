@@ -0,0 +1,154 @@
+package abac
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/sangtandoan/social/internal/store"
+)
+
+const (
+	OpEquals      = "equals"
+	OpNotEquals   = "not_equals"
+	OpIn          = "in"
+	OpNotIn       = "not_in"
+	OpContains    = "contains"
+	OpGreaterThan = "greater_than"
+	OpLessThan    = "less_than"
+)
+
+var operators = []string{
+	OpEquals,
+	OpNotEquals,
+	OpIn,
+	OpNotIn,
+	OpContains,
+	OpGreaterThan,
+	OpLessThan,
+}
+
+// ValidatePolicy rejects policies the engine could not evaluate as their author meant
+func ValidatePolicy(policy *store.Policy) error {
+	if policy.Effect != EffectAllow && policy.Effect != EffectDeny {
+		return fmt.Errorf("effect must be %s or %s", EffectAllow, EffectDeny)
+	}
+
+	if len(policy.Actions) == 0 {
+		return errors.New("a policy needs at least one action")
+	}
+
+	for _, condition := range policy.Conditions {
+		if !slices.Contains(operators, condition.Operator) {
+			return fmt.Errorf("unknown operator %q", condition.Operator)
+		}
+
+		if !strings.Contains(condition.Attribute, ".") && condition.Attribute != "action" {
+			return fmt.Errorf("attribute %q must be namespaced, e.g. subject.id", condition.Attribute)
+		}
+
+		if condition.Operator == OpIn || condition.Operator == OpNotIn {
+			if _, ok := condition.Value.([]any); !ok && condition.ValueFrom == "" {
+				return fmt.Errorf("operator %s needs a list value", condition.Operator)
+			}
+		}
+	}
+
+	return nil
+}
+
+// All conditions must hold. A condition on a missing attribute never holds,
+// so providers must set every attribute a deny policy may look at.
+func matchConditions(conditions []store.PolicyCondition, attrs Attributes) bool {
+	for _, condition := range conditions {
+		if !matchCondition(condition, attrs) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func matchCondition(condition store.PolicyCondition, attrs Attributes) bool {
+	actual, ok := attrs[condition.Attribute]
+	if !ok {
+		return false
+	}
+
+	expected := condition.Value
+	if condition.ValueFrom != "" {
+		expected, ok = attrs[condition.ValueFrom]
+		if !ok {
+			return false
+		}
+	}
+
+	switch condition.Operator {
+	case OpEquals:
+		return equal(actual, expected)
+	case OpNotEquals:
+		return !equal(actual, expected)
+	case OpIn:
+		return contains(expected, actual)
+	case OpNotIn:
+		return !contains(expected, actual)
+	case OpContains:
+		return contains(actual, expected)
+	case OpGreaterThan:
+		a, okA := toFloat(actual)
+		b, okB := toFloat(expected)
+		return okA && okB && a > b
+	case OpLessThan:
+		a, okA := toFloat(actual)
+		b, okB := toFloat(expected)
+		return okA && okB && a < b
+	}
+
+	return false
+}
+
+// Numbers decoded from json are float64 while providers use int64
+func equal(a, b any) bool {
+	if x, ok := toFloat(a); ok {
+		y, ok := toFloat(b)
+		return ok && x == y
+	}
+
+	return reflect.DeepEqual(a, b)
+}
+
+// contains reports whether the list or string haystack holds needle
+func contains(haystack, needle any) bool {
+	if s, ok := haystack.(string); ok {
+		n, ok := needle.(string)
+		return ok && strings.Contains(s, n)
+	}
+
+	value := reflect.ValueOf(haystack)
+	if value.Kind() != reflect.Slice {
+		return false
+	}
+
+	for i := range value.Len() {
+		if equal(value.Index(i).Interface(), needle) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func toFloat(v any) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+
+	return 0, false
+}
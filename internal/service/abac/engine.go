@@ -0,0 +1,227 @@
+// Package abac decides whether a subject may perform an action on a resource
+// from the attributes of both. Policies live in the policies table and are kept
+// in memory, a deny policy that matches always wins over any allow policy.
+package abac
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sangtandoan/social/internal/store"
+	"github.com/sangtandoan/social/internal/utils"
+)
+
+const (
+	EffectAllow = "allow"
+	EffectDeny  = "deny"
+)
+
+const (
+	ResourcePost = "post"
+	ResourceUser = "user"
+
+	ActionRead = "read"
+)
+
+// Attributes are keyed by namespace and name, e.g. subject.id or resource.owner_id
+type Attributes map[string]any
+
+type Request struct {
+	Attributes   Attributes
+	ResourceType string
+	Action       string
+	// SubjectID is 0 for anonymous requests
+	SubjectID  int64
+	ResourceID int64
+}
+
+type Decision struct {
+	Effect string
+	// Policy is the name of the policy that decided, empty when none applied
+	Policy string
+}
+
+func (d *Decision) Allowed() bool {
+	return d.Effect == EffectAllow
+}
+
+type PolicyLoader interface {
+	ListEnabled(ctx context.Context) ([]*store.Policy, error)
+}
+
+// AttributeProvider adds the attributes it knows about to the request.
+// Providers run in order, so a provider can use what the previous ones added.
+type AttributeProvider interface {
+	Provide(ctx context.Context, req *Request) error
+}
+
+// RuleSource supplies rules kept outside the database, e.g. a RuleFile
+type RuleSource interface {
+	Rules() *RuleSet
+}
+
+type Engine struct {
+	loadedAt  time.Time
+	loader    PolicyLoader
+	rules     RuleSource
+	policies  map[string][]*store.Policy
+	providers []AttributeProvider
+	// Other instances don't see Invalidate, they reload after this interval
+	refreshInterval time.Duration
+	mu              sync.RWMutex
+	stale           bool
+}
+
+func NewEngine(
+	loader PolicyLoader,
+	refreshInterval time.Duration,
+	providers ...AttributeProvider,
+) *Engine {
+	return &Engine{
+		loader:          loader,
+		providers:       providers,
+		refreshInterval: refreshInterval,
+		stale:           true,
+	}
+}
+
+// Invalidate makes the next decision reload the policies, call it after any change
+func (e *Engine) Invalidate() {
+	e.mu.Lock()
+	e.stale = true
+	e.mu.Unlock()
+}
+
+// UseRules adds the rules of source to every decision, next to the stored policies
+func (e *Engine) UseRules(source RuleSource) {
+	e.rules = source
+}
+
+// Authorize evaluates every policy of the resource type and the rules with
+// deny-overrides: a matching deny denies, otherwise a matching allow allows,
+// and a request nothing applies to is denied.
+func (e *Engine) Authorize(ctx context.Context, req *Request) (*Decision, error) {
+	policies, err := e.getPolicies(ctx, req.ResourceType)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Attributes == nil {
+		req.Attributes = Attributes{}
+	}
+	req.Attributes["action"] = req.Action
+
+	for _, provider := range e.providers {
+		if err := provider.Provide(ctx, req); err != nil {
+			return nil, err
+		}
+	}
+
+	var allowedBy string
+	for _, policy := range policies {
+		if !slices.Contains(policy.Actions, req.Action) && !slices.Contains(policy.Actions, "*") {
+			continue
+		}
+
+		if !matchConditions(policy.Conditions, req.Attributes) {
+			continue
+		}
+
+		if policy.Effect == EffectDeny {
+			return &Decision{Effect: EffectDeny, Policy: policy.Name}, nil
+		}
+
+		if allowedBy == "" {
+			allowedBy = policy.Name
+		}
+	}
+
+	if e.rules != nil {
+		decision := e.rules.Rules().Evaluate(req.flatten())
+		if decision.Effect == EffectDeny {
+			return decision, nil
+		}
+
+		if decision.Effect == EffectAllow && allowedBy == "" {
+			allowedBy = decision.Policy
+		}
+	}
+
+	if allowedBy != "" {
+		return &Decision{Effect: EffectAllow, Policy: allowedBy}, nil
+	}
+
+	return &Decision{Effect: EffectDeny}, nil
+}
+
+func (e *Engine) getPolicies(ctx context.Context, resourceType string) ([]*store.Policy, error) {
+	e.mu.RLock()
+	fresh := !e.stale && time.Since(e.loadedAt) < e.refreshInterval
+	policies := e.policies[resourceType]
+	e.mu.RUnlock()
+
+	if fresh {
+		return policies, nil
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	// Another request may have reloaded while this one waited for the lock
+	if !e.stale && time.Since(e.loadedAt) < e.refreshInterval {
+		return e.policies[resourceType], nil
+	}
+
+	loaded, err := e.loader.ListEnabled(ctx)
+	if err != nil {
+		// Keep deciding with the last known policies rather than failing every request,
+		// the next attempt waits for another interval
+		if e.policies != nil {
+			utils.Log.Warnf("can not reload policies, using the previous ones: %v", err)
+			e.loadedAt = time.Now()
+			e.stale = false
+			return e.policies[resourceType], nil
+		}
+		return nil, err
+	}
+
+	e.policies = make(map[string][]*store.Policy)
+	for _, policy := range loaded {
+		e.policies[policy.ResourceType] = append(e.policies[policy.ResourceType], policy)
+	}
+	e.loadedAt = time.Now()
+	e.stale = false
+
+	return e.policies[resourceType], nil
+}
+
+// flatten names the attributes the way rule files use them: subject.role is
+// r.role, subject.id is r.user_id, resource.owner_id is r.resource_owner_id
+// and relation.is_owner is r.is_owner.
+func (r *Request) flatten() map[string]any {
+	flat := map[string]any{
+		"action":        r.Action,
+		"resource_type": r.ResourceType,
+	}
+
+	for key, value := range r.Attributes {
+		namespace, name, _ := strings.Cut(key, ".")
+
+		switch namespace {
+		case "subject":
+			if name == "id" {
+				name = "user_id"
+			}
+			flat[name] = value
+		case "resource":
+			flat["resource_"+name] = value
+		case "relation":
+			flat[name] = value
+		}
+	}
+
+	return flat
+}
@@ -0,0 +1,393 @@
+package abac
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// The matcher language of rule files:
+//
+//	expr    = and { "||" and }
+//	and     = unary { "&&" unary }
+//	unary   = "!" unary | compare
+//	compare = operand [ ( "==" | "!=" | "<" | "<=" | ">" | ">=" | "=~" | "in" ) operand ]
+//	operand = "(" expr ")" | string | number | "true" | "false" | "r." name
+//
+// =~ takes a string literal regular expression, in checks membership in a list attribute.
+
+var errMissingAttribute = errors.New("missing attribute")
+
+type env map[string]any
+
+type node interface {
+	eval(vars env) (any, error)
+}
+
+type literal struct{ value any }
+
+func (n *literal) eval(env) (any, error) { return n.value, nil }
+
+type attribute struct{ name string }
+
+func (n *attribute) eval(vars env) (any, error) {
+	value, ok := vars[n.name]
+	if !ok {
+		return nil, fmt.Errorf("%w r.%s", errMissingAttribute, n.name)
+	}
+
+	return value, nil
+}
+
+type not struct{ operand node }
+
+func (n *not) eval(vars env) (any, error) {
+	value, err := evalBool(n.operand, vars)
+	return !value, err
+}
+
+type logical struct {
+	left, right node
+	op          string
+}
+
+func (n *logical) eval(vars env) (any, error) {
+	left, err := evalBool(n.left, vars)
+	if err != nil {
+		return nil, err
+	}
+
+	if n.op == "&&" && !left || n.op == "||" && left {
+		return left, nil
+	}
+
+	return evalBool(n.right, vars)
+}
+
+type compare struct {
+	left, right node
+	pattern     *regexp.Regexp
+	op          string
+}
+
+func (n *compare) eval(vars env) (any, error) {
+	left, err := n.left.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+
+	if n.op == "=~" {
+		s, ok := left.(string)
+		return ok && n.pattern.MatchString(s), nil
+	}
+
+	right, err := n.right.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "==":
+		return equal(left, right), nil
+	case "!=":
+		return !equal(left, right), nil
+	case "in":
+		return contains(right, left), nil
+	}
+
+	if a, ok := toFloat(left); ok {
+		b, ok := toFloat(right)
+		if !ok {
+			return nil, fmt.Errorf("can not compare %v with %v", left, right)
+		}
+		return orderedCompare(n.op, a, b), nil
+	}
+
+	a, okA := left.(string)
+	b, okB := right.(string)
+	if !okA || !okB {
+		return nil, fmt.Errorf("can not compare %v with %v", left, right)
+	}
+
+	return orderedCompare(n.op, a, b), nil
+}
+
+func orderedCompare[T float64 | string](op string, a, b T) bool {
+	switch op {
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	case ">":
+		return a > b
+	default:
+		return a >= b
+	}
+}
+
+func evalBool(n node, vars env) (bool, error) {
+	value, err := n.eval(vars)
+	if err != nil {
+		return false, err
+	}
+
+	b, ok := value.(bool)
+	if !ok {
+		return false, fmt.Errorf("%v is not a boolean", value)
+	}
+
+	return b, nil
+}
+
+var compareOps = []string{"==", "!=", "<", "<=", ">", ">=", "=~", "in"}
+
+type token struct {
+	text string
+	kind tokenKind
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenString
+	tokenNumber
+	tokenOp
+)
+
+func tokenize(input string) ([]token, error) {
+	var tokens []token
+
+	for i := 0; i < len(input); {
+		ch := rune(input[i])
+
+		switch {
+		case unicode.IsSpace(ch):
+			i++
+
+		case ch == '"':
+			end := i + 1
+			for end < len(input) && input[end] != '"' {
+				if input[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(input) {
+				return nil, errors.New("unterminated string")
+			}
+
+			text, err := strconv.Unquote(input[i : end+1])
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{text: text, kind: tokenString})
+			i = end + 1
+
+		case unicode.IsDigit(ch) || ch == '-' && i+1 < len(input) && unicode.IsDigit(rune(input[i+1])):
+			end := i + 1
+			for end < len(input) && (unicode.IsDigit(rune(input[end])) || input[end] == '.') {
+				end++
+			}
+			tokens = append(tokens, token{text: input[i:end], kind: tokenNumber})
+			i = end
+
+		case unicode.IsLetter(ch) || ch == '_':
+			end := i + 1
+			for end < len(input) {
+				c := rune(input[end])
+				if !unicode.IsLetter(c) && !unicode.IsDigit(c) && c != '_' && c != '.' {
+					break
+				}
+				end++
+			}
+			tokens = append(tokens, token{text: input[i:end], kind: tokenIdent})
+			i = end
+
+		default:
+			op := ""
+			for _, candidate := range []string{"&&", "||", "==", "!=", "<=", ">=", "=~", "<", ">", "!", "(", ")"} {
+				if strings.HasPrefix(input[i:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("unexpected character %q", ch)
+			}
+			tokens = append(tokens, token{text: op, kind: tokenOp})
+			i += len(op)
+		}
+	}
+
+	return append(tokens, token{kind: tokenEOF}), nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func parseExpr(input string) (node, error) {
+	tokens, err := tokenize(input)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	n, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.peek().kind != tokenEOF {
+		return nil, fmt.Errorf("unexpected %q", p.peek().text)
+	}
+
+	return n, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) or() (node, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peek().text == "||" && p.peek().kind == tokenOp {
+		p.next()
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		left = &logical{left: left, right: right, op: "||"}
+	}
+
+	return left, nil
+}
+
+func (p *parser) and() (node, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peek().text == "&&" && p.peek().kind == tokenOp {
+		p.next()
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		left = &logical{left: left, right: right, op: "&&"}
+	}
+
+	return left, nil
+}
+
+func (p *parser) unary() (node, error) {
+	if p.peek().text == "!" && p.peek().kind == tokenOp {
+		p.next()
+		operand, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return &not{operand}, nil
+	}
+
+	return p.compare()
+}
+
+func (p *parser) compare() (node, error) {
+	left, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+
+	op := p.peek()
+	if !slices.Contains(compareOps, op.text) || op.kind == tokenString {
+		return left, nil
+	}
+	p.next()
+
+	if op.text == "=~" {
+		pattern := p.next()
+		if pattern.kind != tokenString {
+			return nil, errors.New("=~ needs a string literal pattern")
+		}
+
+		re, err := regexp.Compile("^(?:" + pattern.text + ")$")
+		if err != nil {
+			return nil, err
+		}
+
+		return &compare{left: left, pattern: re, op: op.text}, nil
+	}
+
+	right, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+
+	return &compare{left: left, right: right, op: op.text}, nil
+}
+
+func (p *parser) operand() (node, error) {
+	t := p.next()
+
+	switch t.kind {
+	case tokenString:
+		return &literal{t.text}, nil
+
+	case tokenNumber:
+		n, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, err
+		}
+		return &literal{n}, nil
+
+	case tokenIdent:
+		switch {
+		case t.text == "true":
+			return &literal{true}, nil
+		case t.text == "false":
+			return &literal{false}, nil
+		case strings.HasPrefix(t.text, "r.") && len(t.text) > 2:
+			return &attribute{strings.TrimPrefix(t.text, "r.")}, nil
+		}
+		return nil, fmt.Errorf("unknown identifier %q, request attributes start with r.", t.text)
+
+	case tokenOp:
+		if t.text == "(" {
+			n, err := p.or()
+			if err != nil {
+				return nil, err
+			}
+			if closing := p.next(); closing.text != ")" {
+				return nil, errors.New("missing )")
+			}
+			return n, nil
+		}
+	}
+
+	if t.kind == tokenEOF {
+		return nil, errors.New("unexpected end of expression")
+	}
+
+	return nil, fmt.Errorf("unexpected %q", t.text)
+}
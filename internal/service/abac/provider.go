@@ -0,0 +1,147 @@
+package abac
+
+import (
+	"context"
+
+	"github.com/sangtandoan/social/internal/store"
+)
+
+type UserGetter interface {
+	GetByID(ctx context.Context, id int64) (*store.User, error)
+}
+
+type RoleGetter interface {
+	GetUserRole(ctx context.Context, userID int64) (string, error)
+}
+
+type PostGetter interface {
+	GetByID(ctx context.Context, id int64) (*store.Post, error)
+}
+
+type FollowChecker interface {
+	IsFollowing(ctx context.Context, userID, followerID int64) (bool, error)
+}
+
+// PostAttributes lets a handler that already has the post skip the lookup
+func PostAttributes(post *store.Post) Attributes {
+	return Attributes{
+		"resource.id":         int64(post.ID),
+		"resource.owner_id":   int64(post.UserID),
+		"resource.tags":       post.Tags,
+		"resource.status":     post.Status,
+		"resource.visibility": post.Visibility,
+	}
+}
+
+func UserAttributes(user *store.User) Attributes {
+	return Attributes{
+		"resource.id":        user.ID,
+		"resource.owner_id":  user.ID,
+		"resource.active":    user.Active,
+		"resource.suspended": user.SuspendedAt != nil,
+		"resource.private":   user.IsPrivate,
+	}
+}
+
+type userProvider struct {
+	users UserGetter
+	roles RoleGetter
+}
+
+// NewUserProvider adds subject.* about the caller, and resource.* when the resource is a user
+func NewUserProvider(users UserGetter, roles RoleGetter) AttributeProvider {
+	return &userProvider{users, roles}
+}
+
+func (p *userProvider) Provide(ctx context.Context, req *Request) error {
+	req.Attributes["subject.id"] = req.SubjectID
+	req.Attributes["subject.authenticated"] = req.SubjectID != 0
+	req.Attributes["subject.role"] = ""
+
+	if req.SubjectID != 0 {
+		role, err := p.roles.GetUserRole(ctx, req.SubjectID)
+		if err != nil {
+			return err
+		}
+		req.Attributes["subject.role"] = role
+	}
+
+	if req.ResourceType != ResourceUser {
+		return nil
+	}
+
+	if _, ok := req.Attributes["resource.owner_id"]; ok {
+		return nil
+	}
+
+	user, err := p.users.GetByID(ctx, req.ResourceID)
+	if err != nil {
+		return err
+	}
+
+	for key, value := range UserAttributes(user) {
+		req.Attributes[key] = value
+	}
+
+	return nil
+}
+
+type postProvider struct {
+	posts PostGetter
+}
+
+// NewPostProvider adds resource.* when the resource is a post
+func NewPostProvider(posts PostGetter) AttributeProvider {
+	return &postProvider{posts}
+}
+
+func (p *postProvider) Provide(ctx context.Context, req *Request) error {
+	if req.ResourceType != ResourcePost {
+		return nil
+	}
+
+	if _, ok := req.Attributes["resource.owner_id"]; ok {
+		return nil
+	}
+
+	post, err := p.posts.GetByID(ctx, req.ResourceID)
+	if err != nil {
+		return err
+	}
+
+	for key, value := range PostAttributes(post) {
+		req.Attributes[key] = value
+	}
+
+	return nil
+}
+
+type relationProvider struct {
+	followers FollowChecker
+}
+
+// NewRelationProvider adds relation.* between the caller and the owner of the resource,
+// it must run after the providers that set resource.owner_id
+func NewRelationProvider(followers FollowChecker) AttributeProvider {
+	return &relationProvider{followers}
+}
+
+func (p *relationProvider) Provide(ctx context.Context, req *Request) error {
+	ownerID, _ := req.Attributes["resource.owner_id"].(int64)
+
+	isOwner := req.SubjectID != 0 && req.SubjectID == ownerID
+	req.Attributes["relation.is_owner"] = isOwner
+	req.Attributes["relation.follows_owner"] = false
+
+	if req.SubjectID == 0 || ownerID == 0 || isOwner {
+		return nil
+	}
+
+	following, err := p.followers.IsFollowing(ctx, ownerID, req.SubjectID)
+	if err != nil {
+		return err
+	}
+	req.Attributes["relation.follows_owner"] = following
+
+	return nil
+}
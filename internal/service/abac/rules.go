@@ -0,0 +1,273 @@
+package abac
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sangtandoan/social/internal/utils"
+)
+
+// Rule is one line of a rule file:
+//
+//	p, sub_rule, obj_rule, act_rule, eft
+//
+// sub_rule, obj_rule and act_rule are matcher expressions over the request
+// attributes, or * to match anything. act_rule may also be a quoted action,
+// "write" is short for r.action == "write". eft is allow or deny.
+type Rule struct {
+	subject node
+	object  node
+	action  node
+	Effect  string
+	Line    int
+}
+
+// RuleSet evaluates rules with deny-overrides, like the policies in the database
+type RuleSet struct {
+	rules []*Rule
+}
+
+func ParseRules(r io.Reader) (*RuleSet, error) {
+	var rules []*Rule
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		rule, err := parseRule(text)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		rule.Line = line
+
+		rules = append(rules, rule)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return &RuleSet{rules}, nil
+}
+
+func parseRule(text string) (*Rule, error) {
+	fields, err := splitFields(text)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(fields) != 5 || fields[0] != "p" {
+		return nil, errors.New("expected p, sub_rule, obj_rule, act_rule, eft")
+	}
+
+	rule := &Rule{Effect: fields[4]}
+	if rule.Effect != EffectAllow && rule.Effect != EffectDeny {
+		return nil, fmt.Errorf("effect must be %s or %s", EffectAllow, EffectDeny)
+	}
+
+	if rule.subject, err = parseMatcher(fields[1]); err != nil {
+		return nil, fmt.Errorf("sub_rule: %w", err)
+	}
+
+	if rule.object, err = parseMatcher(fields[2]); err != nil {
+		return nil, fmt.Errorf("obj_rule: %w", err)
+	}
+
+	action := fields[3]
+	if len(action) > 1 && strings.HasPrefix(action, `"`) && strings.HasSuffix(action, `"`) &&
+		!strings.Contains(action[1:len(action)-1], `"`) {
+		action = "r.action == " + action
+	}
+	if rule.action, err = parseMatcher(action); err != nil {
+		return nil, fmt.Errorf("act_rule: %w", err)
+	}
+
+	return rule, nil
+}
+
+func parseMatcher(text string) (node, error) {
+	if text == "*" {
+		return &literal{true}, nil
+	}
+
+	return parseExpr(text)
+}
+
+// splitFields splits on the commas that are not inside a string
+func splitFields(text string) ([]string, error) {
+	var fields []string
+
+	start, inString := 0, false
+	for i := 0; i < len(text); i++ {
+		switch text[i] {
+		case '\\':
+			if inString {
+				i++
+			}
+		case '"':
+			inString = !inString
+		case ',':
+			if !inString {
+				fields = append(fields, strings.TrimSpace(text[start:i]))
+				start = i + 1
+			}
+		}
+	}
+
+	if inString {
+		return nil, errors.New("unterminated string")
+	}
+
+	return append(fields, strings.TrimSpace(text[start:])), nil
+}
+
+// Evaluate returns the decision of the rules that match the request attributes,
+// the effect is empty when none does. A matcher that can not be evaluated against
+// the request, e.g. because an attribute is missing, fails closed: an allow rule
+// does not match and a deny rule does, unless another of its matchers is false.
+func (s *RuleSet) Evaluate(attrs map[string]any) *Decision {
+	var allowedBy *Rule
+
+	for _, rule := range s.rules {
+		if !rule.matches(attrs) {
+			continue
+		}
+
+		if rule.Effect == EffectDeny {
+			return &Decision{Effect: EffectDeny, Policy: rule.name()}
+		}
+
+		if allowedBy == nil {
+			allowedBy = rule
+		}
+	}
+
+	if allowedBy != nil {
+		return &Decision{Effect: EffectAllow, Policy: allowedBy.name()}
+	}
+
+	return &Decision{}
+}
+
+func (s *RuleSet) Len() int {
+	return len(s.rules)
+}
+
+func (r *Rule) matches(attrs map[string]any) bool {
+	failed := false
+	for _, matcher := range []node{r.subject, r.object, r.action} {
+		ok, err := evalBool(matcher, attrs)
+		if err != nil {
+			if !errors.Is(err, errMissingAttribute) {
+				utils.Log.Warnf("can not evaluate rule at line %d: %v", r.Line, err)
+			}
+			failed = true
+			continue
+		}
+
+		if !ok {
+			return false
+		}
+	}
+
+	return !failed || r.Effect == EffectDeny
+}
+
+func (r *Rule) name() string {
+	return fmt.Sprintf("rule file line %d", r.Line)
+}
+
+// RuleFile keeps the rules of a file and reloads them when the file changes.
+// A file that fails to parse is reported and the previous rules stay in use.
+type RuleFile struct {
+	modTime time.Time
+	rules   *RuleSet
+	path    string
+	mu      sync.RWMutex
+}
+
+func LoadRuleFile(path string) (*RuleFile, error) {
+	f := &RuleFile{path: path}
+	if err := f.reload(); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+func (f *RuleFile) Rules() *RuleSet {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	return f.rules
+}
+
+// Watch checks the file for changes every interval until ctx is done
+func (f *RuleFile) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			info, err := os.Stat(f.path)
+			if err != nil {
+				utils.Log.Warnf("can not stat rule file %s: %v", f.path, err)
+				continue
+			}
+
+			f.mu.RLock()
+			changed := !info.ModTime().Equal(f.modTime)
+			f.mu.RUnlock()
+
+			if !changed {
+				continue
+			}
+
+			if err := f.reload(); err != nil {
+				utils.Log.Errorf("can not reload rule file %s, keeping the previous rules: %v", f.path, err)
+				continue
+			}
+			utils.Log.Infof("reloaded rule file %s", f.path)
+		}
+	}
+}
+
+func (f *RuleFile) reload() error {
+	file, err := os.Open(f.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	rules, err := ParseRules(file)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	// Remember the broken version too, so it is reported once and not on every tick
+	f.modTime = info.ModTime()
+	if err != nil {
+		return err
+	}
+	f.rules = rules
+
+	return nil
+}
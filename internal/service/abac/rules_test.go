@@ -0,0 +1,227 @@
+package abac
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sangtandoan/social/internal/utils"
+	"go.uber.org/zap"
+)
+
+func init() {
+	utils.Log = zap.NewNop().Sugar()
+}
+
+func TestMatcher(t *testing.T) {
+	attrs := env{
+		"a":      1,
+		"b":      2.0,
+		"name":   "alice",
+		"email":  "alice@example.com",
+		"roles":  []string{"admin", "editor"},
+		"banned": false,
+	}
+
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		// && binds tighter than ||, ! applies to the comparison that follows
+		{`r.a == 1 || r.a == 2 && r.b == 3`, true},
+		{`(r.a == 1 || r.a == 2) && r.b == 3`, false},
+		{`!r.a == 2`, true},
+		{`!r.banned && r.a < r.b`, true},
+		{`r.a >= 1 && r.b <= 2 && r.a != r.b`, true},
+		{`r.name < "bob"`, true},
+		{`r.a == 1.0`, true},
+		{`-1 < r.a`, true},
+
+		{`"admin" in r.roles`, true},
+		{`"owner" in r.roles`, false},
+		{`"lic" in r.name`, true},
+
+		{`r.email =~ ".*@example\\.com"`, true},
+		{`r.email =~ "example\\.com"`, false},
+		{`r.a =~ "1"`, false},
+	}
+
+	for _, tt := range tests {
+		n, err := parseExpr(tt.expr)
+		if err != nil {
+			t.Errorf("parseExpr(%q): %v", tt.expr, err)
+			continue
+		}
+
+		got, err := evalBool(n, attrs)
+		if err != nil {
+			t.Errorf("%s: %v", tt.expr, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestParseRulesErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		rule string
+	}{
+		{"too few fields", `p, *, *, allow`},
+		{"not a policy", `g, *, *, *, allow`},
+		{"unknown effect", `p, *, *, *, permit`},
+		{"unterminated string", `p, r.name == "alice, *, *, allow`},
+		{"unknown identifier", `p, user == 1, *, *, allow`},
+		{"missing paren", `p, (r.a == 1, *, *, allow`},
+		{"trailing tokens", `p, r.a == 1 r.b, *, *, allow`},
+		{"pattern not a string", `p, r.name =~ r.other, *, *, allow`},
+		{"invalid pattern", `p, r.name =~ "(", *, *, allow`},
+		{"unexpected character", `p, r.a == 1 & r.b == 2, *, *, allow`},
+		{"empty matcher", `p, , *, *, allow`},
+	}
+
+	for _, tt := range tests {
+		_, err := ParseRules(strings.NewReader("# comment\n\n" + tt.rule))
+		if err == nil {
+			t.Errorf("%s: ParseRules(%q) succeeded", tt.name, tt.rule)
+			continue
+		}
+		if !strings.HasPrefix(err.Error(), "line 3:") {
+			t.Errorf("%s: error %q does not give the line", tt.name, err)
+		}
+	}
+}
+
+func TestRuleSetEvaluate(t *testing.T) {
+	rules, err := ParseRules(strings.NewReader(strings.Join([]string{
+		`p, *, r.resource_type == "post", "read", allow`,
+		`p, r.user_id == r.resource_user_id, *, r.action == "update" || r.action == "delete", allow`,
+		`p, r.suspended, *, *, deny`,
+		`p, r.clearance < r.resource_classification, *, "read", deny`,
+		`p, "moderator" in r.roles, *, "delete", allow`,
+		`p, r.name == "a, b", *, "comma", allow`,
+	}, "\n")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		attrs  map[string]any
+		effect string
+		line   int
+	}{
+		{
+			"quoted action shorthand",
+			map[string]any{
+				"action": "read", "resource_type": "post", "suspended": false,
+				"clearance": 2, "resource_classification": 1,
+			},
+			EffectAllow,
+			1,
+		},
+		{
+			"no rule applies",
+			map[string]any{"action": "publish", "resource_type": "post", "suspended": false},
+			"",
+			0,
+		},
+		{
+			"first matching allow decides",
+			map[string]any{
+				"action": "delete", "user_id": 1, "resource_user_id": 1,
+				"roles": []string{"moderator"}, "suspended": false,
+			},
+			EffectAllow,
+			2,
+		},
+		{
+			"deny overrides allow",
+			map[string]any{
+				"action": "delete", "user_id": 1, "resource_user_id": 1, "suspended": true,
+			},
+			EffectDeny,
+			3,
+		},
+		{
+			"deny with a missing attribute fails closed",
+			map[string]any{"action": "read", "resource_type": "post", "suspended": false},
+			EffectDeny,
+			4,
+		},
+		{
+			"deny on another action stays out",
+			map[string]any{
+				"action": "delete", "roles": []string{"moderator"}, "suspended": false,
+			},
+			EffectAllow,
+			5,
+		},
+		{
+			"allow with a missing attribute does not match",
+			map[string]any{"action": "update", "user_id": 1, "suspended": false},
+			"",
+			0,
+		},
+		{
+			"commas inside strings",
+			map[string]any{"action": "comma", "name": "a, b", "suspended": false},
+			EffectAllow,
+			6,
+		},
+	}
+
+	for _, tt := range tests {
+		decision := rules.Evaluate(tt.attrs)
+		if decision.Effect != tt.effect {
+			t.Errorf("%s: effect = %q, want %q", tt.name, decision.Effect, tt.effect)
+			continue
+		}
+
+		want := ""
+		if tt.line != 0 {
+			want = (&Rule{Line: tt.line}).name()
+		}
+		if decision.Policy != want {
+			t.Errorf("%s: decided by %q, want %q", tt.name, decision.Policy, want)
+		}
+	}
+}
+
+func TestRuleFileKeepsRulesOnBadReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.csv")
+	if err := os.WriteFile(path, []byte(`p, *, *, "read", allow`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := LoadRuleFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(path, []byte(`p, r.a ==, *, *, allow`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.reload(); err == nil {
+		t.Fatal("reloading a broken file succeeded")
+	}
+
+	decision := f.Rules().Evaluate(map[string]any{"action": "read"})
+	if decision.Effect != EffectAllow {
+		t.Fatalf("effect = %q after a bad reload, want the previous rules to allow", decision.Effect)
+	}
+
+	if err := os.WriteFile(path, []byte(`p, *, *, "read", deny`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.reload(); err != nil {
+		t.Fatal(err)
+	}
+
+	if decision := f.Rules().Evaluate(map[string]any{"action": "read"}); decision.Effect != EffectDeny {
+		t.Fatalf("effect = %q after fixing the file, want deny", decision.Effect)
+	}
+}
@@ -0,0 +1,50 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// Scopes limit what an api key can do, access tokens from a login are not scoped
+const (
+	ScopeFeedRead   = "feed:read"
+	ScopePostsRead  = "posts:read"
+	ScopePostsWrite = "posts:write"
+)
+
+var validScopes = map[string]bool{
+	ScopeFeedRead:   true,
+	ScopePostsRead:  true,
+	ScopePostsWrite: true,
+}
+
+func IsValidScope(scope string) bool {
+	return validScopes[scope]
+}
+
+const APIKeyPrefix = "sk_"
+
+// GenerateAPIKey returns the full key and the public part used to recognize it,
+// the key looks like sk_<prefix>_<secret>
+func GenerateAPIKey() (string, string, error) {
+	prefixBytes := make([]byte, 4)
+	if _, err := rand.Read(prefixBytes); err != nil {
+		return "", "", fmt.Errorf("failed to generate api key: %v", err)
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", fmt.Errorf("failed to generate api key: %v", err)
+	}
+
+	prefix := APIKeyPrefix + hex.EncodeToString(prefixBytes)
+
+	return prefix + "_" + base64.RawURLEncoding.EncodeToString(secret), prefix, nil
+}
+
+func IsAPIKey(token string) bool {
+	return strings.HasPrefix(token, APIKeyPrefix)
+}
@@ -0,0 +1,117 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/sangtandoan/social/internal/config"
+)
+
+var ErrInvalidToken = errors.New("invalid or expired token")
+
+type TokenType string
+
+const (
+	AccessToken TokenType = "access"
+	// MFAToken proves the password was checked, it is only exchangeable for
+	// an access token together with a second factor
+	MFAToken TokenType = "mfa"
+)
+
+const mfaTokenTTL = 5 * time.Minute
+
+type Claims struct {
+	SessionID string    `json:"sid,omitempty"`
+	Type      TokenType `json:"typ"`
+	jwt.RegisteredClaims
+	UserID int64 `json:"uid"`
+}
+
+type TokenMaker struct {
+	issuer     string
+	secret     []byte
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+}
+
+func NewTokenMaker(cfg *config.AuthConfig) *TokenMaker {
+	return &TokenMaker{
+		issuer:     cfg.Issuer,
+		secret:     []byte(cfg.Secret),
+		accessTTL:  cfg.AccessTokenTTL,
+		refreshTTL: cfg.RefreshTokenTTL,
+	}
+}
+
+// CreateAccessToken signs a short-lived HS256 token carrying the user id
+// and the refresh token family (session) it was issued for
+func (m *TokenMaker) CreateAccessToken(userID int64, sessionID string) (string, time.Time, error) {
+	return m.createToken(&Claims{Type: AccessToken, UserID: userID, SessionID: sessionID}, m.accessTTL)
+}
+
+func (m *TokenMaker) VerifyAccessToken(tokenString string) (*Claims, error) {
+	return m.verifyToken(tokenString, AccessToken)
+}
+
+func (m *TokenMaker) CreateMFAToken(userID int64) (string, time.Time, error) {
+	return m.createToken(&Claims{Type: MFAToken, UserID: userID}, mfaTokenTTL)
+}
+
+func (m *TokenMaker) VerifyMFAToken(tokenString string) (*Claims, error) {
+	return m.verifyToken(tokenString, MFAToken)
+}
+
+func (m *TokenMaker) createToken(claims *Claims, ttl time.Duration) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(ttl)
+
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		Issuer:    m.issuer,
+		Subject:   strconv.FormatInt(claims.UserID, 10),
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(expiresAt),
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(m.secret)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	return token, expiresAt, nil
+}
+
+// verifyToken also checks the token type, so a mfa token can never be used as an access token
+func (m *TokenMaker) verifyToken(tokenString string, tokenType TokenType) (*Claims, error) {
+	claims := &Claims{}
+
+	_, err := jwt.ParseWithClaims(
+		tokenString,
+		claims,
+		func(token *jwt.Token) (any, error) {
+			return m.secret, nil
+		},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithIssuer(m.issuer),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil || claims.Type != tokenType {
+		return nil, ErrInvalidToken
+	}
+
+	return claims, nil
+}
+
+// CreateRefreshToken returns an opaque random token, only its hash is persisted
+func (m *TokenMaker) CreateRefreshToken() (string, time.Time, error) {
+	tokenBytes := make([]byte, 32)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to generate refresh token: %v", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(tokenBytes), time.Now().Add(m.refreshTTL), nil
+}
@@ -22,6 +22,17 @@ func NewRedisClient(cfg *config.RedisConfig) *redis.Client {
 	})
 }
 
+// Cache is what the handlers keep outside the database, CacheService stores it in redis
+type Cache interface {
+	Get(ctx context.Context, key string) (string, error)
+	GetDel(ctx context.Context, key string) (string, error)
+	Set(ctx context.Context, key string, value any, expiration time.Duration) error
+	SetNX(ctx context.Context, key string, value any, expiration time.Duration) error
+	Delete(ctx context.Context, key string) error
+	Incr(ctx context.Context, key string, window time.Duration) (int64, error)
+	TTL(ctx context.Context, key string) (time.Duration, error)
+}
+
 type CacheService struct {
 	client *redis.Client
 }
@@ -34,6 +45,11 @@ func (s *CacheService) Get(ctx context.Context, key string) (string, error) {
 	return s.client.Get(ctx, key).Result()
 }
 
+// GetDel reads a key and removes it in one step, for values that must be used once
+func (s *CacheService) GetDel(ctx context.Context, key string) (string, error) {
+	return s.client.GetDel(ctx, key).Result()
+}
+
 func (s *CacheService) Set(
 	ctx context.Context,
 	key string,
@@ -61,3 +77,23 @@ func (s *CacheService) SetNX(
 func (s *CacheService) Delete(ctx context.Context, key string) error {
 	return s.client.Del(ctx, key).Err()
 }
+
+// Incr counts hits in a fixed window, the window starts with the first hit
+func (s *CacheService) Incr(ctx context.Context, key string, window time.Duration) (int64, error) {
+	count, err := s.client.Incr(ctx, key).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	if count == 1 {
+		if err := s.client.Expire(ctx, key, window).Err(); err != nil {
+			return 0, err
+		}
+	}
+
+	return count, nil
+}
+
+func (s *CacheService) TTL(ctx context.Context, key string) (time.Duration, error) {
+	return s.client.TTL(ctx, key).Result()
+}
@@ -23,6 +23,8 @@ type TemplateOpt int
 const (
 	ConfirmTemplate TemplateOpt = iota
 	DeleteTemplate
+	ResetPasswordTemplate
+	AccountLockedTemplate
 )
 
 type SendRequest struct {
@@ -36,6 +38,18 @@ type ConfirmData struct {
 	Token    string
 }
 
+type ResetPasswordData struct {
+	Username  string
+	Token     string
+	ExpiresIn time.Duration
+}
+
+type AccountLockedData struct {
+	LockedUntil time.Time
+	Username    string
+	IPAddress   string
+}
+
 type EmailTemplate struct {
 	Subject string
 	Body    string
@@ -106,6 +120,10 @@ func getTemplateEmail(opt TemplateOpt) *EmailTemplate {
 	switch opt {
 	case ConfirmTemplate:
 		template.Path = "confirm-email.tmpl"
+	case ResetPasswordTemplate:
+		template.Path = "reset-password.tmpl"
+	case AccountLockedTemplate:
+		template.Path = "account-locked.tmpl"
 	}
 
 	return &template
@@ -123,6 +141,30 @@ func (m *SMTPMailer) getData(opt TemplateOpt, data any) any {
 				ActivationURL: fmt.Sprintf("%s/activate/%s", m.config.ServerAddr, newData.Token),
 			}
 		}
+	case ResetPasswordTemplate:
+		if newData, ok := data.(*ResetPasswordData); ok {
+			return struct {
+				Username  string
+				ResetURL  string
+				ExpiresIn string
+			}{
+				Username:  newData.Username,
+				ResetURL:  fmt.Sprintf("%s/password/reset/%s", m.config.ServerAddr, newData.Token),
+				ExpiresIn: newData.ExpiresIn.String(),
+			}
+		}
+	case AccountLockedTemplate:
+		if newData, ok := data.(*AccountLockedData); ok {
+			return struct {
+				Username    string
+				IPAddress   string
+				LockedUntil string
+			}{
+				Username:    newData.Username,
+				IPAddress:   newData.IPAddress,
+				LockedUntil: newData.LockedUntil.Format(time.RFC1123),
+			}
+		}
 	}
 
 	return nil
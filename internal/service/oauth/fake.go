@@ -0,0 +1,181 @@
+package oauth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+const FakeClientID = "fake-client"
+
+type fakeGrant struct {
+	identity      *Identity
+	redirectURI   string
+	codeChallenge string
+}
+
+// FakeIssuer is a minimal OpenID Connect provider that runs inside the process.
+// It lets the whole authorization code + PKCE flow run in development and tests
+// without network access. Never enable it in production: it signs in whoever
+// the login_hint says.
+//
+//	GET  {base}/authorize?login_hint=alice@example.com&email_verified=true&...
+//	POST {base}/token
+//	GET  {base}/userinfo
+type FakeIssuer struct {
+	codes   map[string]*fakeGrant
+	tokens  map[string]*Identity
+	baseURL string
+	mu      sync.Mutex
+}
+
+func NewFakeIssuer(baseURL string) *FakeIssuer {
+	return &FakeIssuer{
+		codes:   make(map[string]*fakeGrant),
+		tokens:  make(map[string]*Identity),
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+	}
+}
+
+// Provider returns a provider whose back channel calls never leave the process
+func (f *FakeIssuer) Provider(name string) *OIDCProvider {
+	return NewOIDCProvider(OIDCConfig{
+		Name:        name,
+		ClientID:    FakeClientID,
+		AuthURL:     f.baseURL + "/authorize",
+		TokenURL:    f.baseURL + "/token",
+		UserInfoURL: f.baseURL + "/userinfo",
+	}, &http.Client{Transport: f})
+}
+
+// RoundTrip serves client requests with the issuer handler directly
+func (f *FakeIssuer) RoundTrip(req *http.Request) (*http.Response, error) {
+	recorder := httptest.NewRecorder()
+	f.ServeHTTP(recorder, req)
+
+	res := recorder.Result()
+	res.Request = req
+	return res, nil
+}
+
+func (f *FakeIssuer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case strings.HasSuffix(r.URL.Path, "/authorize"):
+		f.authorize(w, r)
+	case strings.HasSuffix(r.URL.Path, "/token"):
+		f.token(w, r)
+	case strings.HasSuffix(r.URL.Path, "/userinfo"):
+		f.userinfo(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (f *FakeIssuer) authorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	email := query.Get("login_hint")
+	redirectURI := query.Get("redirect_uri")
+	if email == "" || redirectURI == "" || query.Get("client_id") != FakeClientID {
+		writeOAuthError(w, "invalid_request")
+		return
+	}
+
+	if query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" {
+		writeOAuthError(w, "invalid_request")
+		return
+	}
+
+	code, err := GenerateVerifier()
+	if err != nil {
+		writeOAuthError(w, "server_error")
+		return
+	}
+
+	f.mu.Lock()
+	f.codes[code] = &fakeGrant{
+		identity: &Identity{
+			Subject:       "fake|" + strings.ToLower(email),
+			Email:         email,
+			Name:          strings.Split(email, "@")[0],
+			EmailVerified: query.Get("email_verified") != "false",
+		},
+		redirectURI:   redirectURI,
+		codeChallenge: query.Get("code_challenge"),
+	}
+	f.mu.Unlock()
+
+	callback, err := url.Parse(redirectURI)
+	if err != nil {
+		writeOAuthError(w, "invalid_request")
+		return
+	}
+
+	values := callback.Query()
+	values.Set("code", code)
+	values.Set("state", query.Get("state"))
+	callback.RawQuery = values.Encode()
+
+	http.Redirect(w, r, callback.String(), http.StatusFound)
+}
+
+func (f *FakeIssuer) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil || r.PostForm.Get("grant_type") != "authorization_code" {
+		writeOAuthError(w, "invalid_request")
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	code := r.PostForm.Get("code")
+	grant, ok := f.codes[code]
+	// Codes are single use, even a failed exchange burns it
+	delete(f.codes, code)
+
+	if !ok ||
+		grant.redirectURI != r.PostForm.Get("redirect_uri") ||
+		S256Challenge(r.PostForm.Get("code_verifier")) != grant.codeChallenge {
+		writeOAuthError(w, "invalid_grant")
+		return
+	}
+
+	accessToken, err := GenerateVerifier()
+	if err != nil {
+		writeOAuthError(w, "server_error")
+		return
+	}
+	f.tokens[accessToken] = grant.identity
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"access_token": accessToken,
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+	})
+}
+
+func (f *FakeIssuer) userinfo(w http.ResponseWriter, r *http.Request) {
+	accessToken, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+
+	f.mu.Lock()
+	identity, ok := f.tokens[accessToken]
+	f.mu.Unlock()
+
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(identity)
+}
+
+func writeOAuthError(w http.ResponseWriter, code string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(map[string]string{"error": code})
+}
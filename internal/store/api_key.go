@@ -0,0 +1,180 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/sangtandoan/social/internal/models/params"
+	"github.com/sangtandoan/social/internal/utils"
+)
+
+type apiKeyStore struct {
+	db *sql.DB
+}
+
+func NewAPIKeyStore(db *sql.DB) *apiKeyStore {
+	return &apiKeyStore{db}
+}
+
+type APIKey struct {
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ID         int64      `json:"id"`
+	UserID     int64      `json:"-"`
+}
+
+func (s *apiKeyStore) Create(ctx context.Context, arg *params.CreateAPIKeyParams) (*APIKey, error) {
+	query := `
+		INSERT INTO api_keys (user_id, name, prefix, key_hash, scopes, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
+	defer cancel()
+
+	row := s.db.QueryRowContext(
+		ctx,
+		query,
+		arg.UserID,
+		arg.Name,
+		arg.Prefix,
+		arg.KeyHash,
+		pq.Array(arg.Scopes),
+		arg.ExpiresAt,
+	)
+
+	key := APIKey{
+		UserID:    arg.UserID,
+		Name:      arg.Name,
+		Prefix:    arg.Prefix,
+		Scopes:    arg.Scopes,
+		ExpiresAt: arg.ExpiresAt,
+	}
+	err := row.Scan(&key.ID, &key.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &key, nil
+}
+
+func (s *apiKeyStore) ListByUserID(ctx context.Context, userID int64) ([]*APIKey, error) {
+	query := `
+		SELECT id, user_id, name, prefix, scopes, expires_at, last_used_at, created_at
+		FROM api_keys
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := []*APIKey{}
+	for rows.Next() {
+		var key APIKey
+
+		err := rows.Scan(
+			&key.ID,
+			&key.UserID,
+			&key.Name,
+			&key.Prefix,
+			pq.Array(&key.Scopes),
+			&key.ExpiresAt,
+			&key.LastUsedAt,
+			&key.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		res = append(res, &key)
+	}
+
+	return res, rows.Err()
+}
+
+// GetByHash only returns keys that have not expired yet and whose owner is not suspended
+func (s *apiKeyStore) GetByHash(ctx context.Context, keyHash string) (*APIKey, error) {
+	query := `
+		SELECT k.id, k.user_id, k.name, k.prefix, k.scopes, k.expires_at, k.last_used_at, k.created_at
+		FROM api_keys k
+		JOIN users u ON u.id = k.user_id
+		WHERE k.key_hash = $1
+			AND (k.expires_at IS NULL OR k.expires_at > NOW())
+			AND u.suspended_at IS NULL
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
+	defer cancel()
+
+	var key APIKey
+	err := s.db.QueryRowContext(ctx, query, keyHash).Scan(
+		&key.ID,
+		&key.UserID,
+		&key.Name,
+		&key.Prefix,
+		pq.Array(&key.Scopes),
+		&key.ExpiresAt,
+		&key.LastUsedAt,
+		&key.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, utils.ErrInvalidToken
+		}
+		return nil, err
+	}
+
+	return &key, nil
+}
+
+// Touch records the usage of a key, at most once a minute to spare writes on busy bots
+func (s *apiKeyStore) Touch(ctx context.Context, id int64) error {
+	query := `
+		UPDATE api_keys SET last_used_at = NOW()
+		WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < NOW() - interval '1 minute')
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, query, id)
+	return err
+}
+
+func (s *apiKeyStore) Delete(ctx context.Context, userID, id int64) error {
+	query := "DELETE FROM api_keys WHERE id = $1 AND user_id = $2"
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return utils.ErrNotFound
+	}
+
+	return nil
+}
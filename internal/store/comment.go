@@ -0,0 +1,341 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/sangtandoan/social/internal/models/dto"
+	"github.com/sangtandoan/social/internal/utils"
+)
+
+type commentStore struct {
+	db *sql.DB
+}
+
+func NewCommentStore(db *sql.DB) *commentStore {
+	return &commentStore{db}
+}
+
+type Comment struct {
+	CreatedAt time.Time  `json:"created_at"`
+	EditedAt  *time.Time `json:"edited_at"`
+	// ParentID is the comment this one replies to, nil at the top level
+	ParentID *int64 `json:"parent_id"`
+	Content  string `json:"content"`
+	Username string `json:"username"`
+	// Replies holds the loaded part of the thread below the comment, ReplyCount
+	// tells clients whether there is more to load
+	Replies        []*Comment     `json:"replies,omitempty"`
+	ReactionCounts ReactionCounts `json:"reaction_counts"`
+	ID             int64          `json:"id"`
+	PostID         int64          `json:"post_id"`
+	UserID         int64          `json:"user_id"`
+	ReplyCount     int64          `json:"reply_count"`
+}
+
+// commentColumns are read from a comments row c joined with its author u
+const commentColumns = `
+	c.id, c.post_id, c.user_id, c.parent_id, c.content, c.created_at, c.edited_at,
+	c.reaction_counts, u.username,
+	(SELECT COUNT(*) FROM comments r WHERE r.parent_id = c.id) AS reply_count
+`
+
+func scanComment(row rowScanner) (*Comment, error) {
+	var comment Comment
+	err := row.Scan(
+		&comment.ID,
+		&comment.PostID,
+		&comment.UserID,
+		&comment.ParentID,
+		&comment.Content,
+		&comment.CreatedAt,
+		&comment.EditedAt,
+		&comment.ReactionCounts,
+		&comment.Username,
+		&comment.ReplyCount,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &comment, nil
+}
+
+// Create adds a comment to the post, a reply's parent must be on the same post
+func (s *commentStore) Create(ctx context.Context, comment *Comment) error {
+	query := `
+		WITH c AS (
+			INSERT INTO comments (post_id, user_id, content, parent_id)
+			SELECT $1, $2, $3, $4
+			WHERE $4::bigint IS NULL OR EXISTS (
+				SELECT 1 FROM comments WHERE id = $4 AND post_id = $1
+			)
+			RETURNING *
+		)
+		SELECT ` + commentColumns + ` FROM c JOIN users u ON u.id = c.user_id
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
+	defer cancel()
+
+	created, err := scanComment(s.db.QueryRowContext(
+		ctx,
+		query,
+		comment.PostID,
+		comment.UserID,
+		comment.Content,
+		comment.ParentID,
+	))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return utils.ErrParentCommentNotFound
+		}
+		return err
+	}
+
+	*comment = *created
+	return nil
+}
+
+func (s *commentStore) Get(ctx context.Context, id, postID int64) (*Comment, error) {
+	query := `
+		SELECT ` + commentColumns + `
+		FROM comments c
+		JOIN users u ON u.id = c.user_id
+		WHERE c.id = $1 AND c.post_id = $2
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
+	defer cancel()
+
+	comment, err := scanComment(s.db.QueryRowContext(ctx, query, id, postID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, utils.ErrNotFound
+		}
+		return nil, err
+	}
+
+	return comment, nil
+}
+
+// Update changes the content of a comment, only its author can edit it
+func (s *commentStore) Update(
+	ctx context.Context,
+	id, postID, userID int64,
+	content string,
+) (*Comment, error) {
+	query := `
+		WITH c AS (
+			UPDATE comments SET content = $4, edited_at = NOW()
+			WHERE id = $1 AND post_id = $2 AND user_id = $3
+			RETURNING *
+		)
+		SELECT ` + commentColumns + ` FROM c JOIN users u ON u.id = c.user_id
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
+	defer cancel()
+
+	comment, err := scanComment(s.db.QueryRowContext(ctx, query, id, postID, userID, content))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			// Someone else's comment is reported like a missing one
+			return nil, utils.ErrNotFound
+		}
+		return nil, err
+	}
+
+	return comment, nil
+}
+
+// Delete removes a comment for its author, the owner of the post, or a moderator
+func (s *commentStore) Delete(ctx context.Context, id, postID, userID int64) error {
+	query := `
+		DELETE FROM comments c
+		USING posts p
+		WHERE c.id = $1 AND c.post_id = $2 AND p.id = c.post_id AND
+			(c.user_id = $3 OR p.user_id = $3 OR ` + userHasPermission(3, 4) + `)
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx, query, id, postID, userID, string(PermCommentModerate))
+	if err != nil {
+		return err
+	}
+
+	return checkAffected(result)
+}
+
+// ListByPostID returns a page of the post's top level comments, newest first
+func (s *commentStore) ListByPostID(
+	ctx context.Context,
+	postID int64,
+	page *dto.Pagination,
+) ([]*Comment, *utils.Cursor, error) {
+	query := `
+		SELECT ` + commentColumns + `
+		FROM comments c
+		JOIN users u ON u.id = c.user_id
+		WHERE c.post_id = $1 AND c.parent_id IS NULL AND ` + afterCursor("c", 2, 3) + `
+		ORDER BY c.created_at DESC, c.id DESC
+		LIMIT $4
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
+	defer cancel()
+
+	createdAt, id := cursorArgs(page.After)
+
+	rows, err := s.db.QueryContext(ctx, query, postID, createdAt, id, page.Limit+1)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	res := []*Comment{}
+	for rows.Next() {
+		comment, err := scanComment(rows)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		res = append(res, comment)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	res, next := nextPage(res, page.Limit, commentPosition)
+	return res, next, nil
+}
+
+func commentPosition(comment *Comment) utils.Cursor {
+	return utils.Cursor{CreatedAt: comment.CreatedAt, ID: comment.ID}
+}
+
+// ListReplies returns a page of the direct replies to a comment, oldest first.
+// It is how clients read the replies a thread left out.
+func (s *commentStore) ListReplies(
+	ctx context.Context,
+	parentID int64,
+	page *dto.Pagination,
+) ([]*Comment, *utils.Cursor, error) {
+	query := `
+		SELECT ` + commentColumns + `
+		FROM comments c
+		JOIN users u ON u.id = c.user_id
+		WHERE c.parent_id = $1 AND ` + afterCursorAsc("c", 2, 3) + `
+		ORDER BY c.created_at, c.id
+		LIMIT $4
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
+	defer cancel()
+
+	createdAt, id := cursorArgs(page.After)
+
+	rows, err := s.db.QueryContext(ctx, query, parentID, createdAt, id, page.Limit+1)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	res := []*Comment{}
+	for rows.Next() {
+		comment, err := scanComment(rows)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		res = append(res, comment)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	res, next := nextPage(res, page.Limit, commentPosition)
+	return res, next, nil
+}
+
+// maxRepliesPerParent caps the replies a thread loads below each comment, so a
+// popular comment does not pull its whole branch into every page
+const maxRepliesPerParent = 10
+
+// AttachReplies loads the threads below the roots, depth levels deep, the oldest
+// maxRepliesPerParent replies of each comment. The replies left out are only counted
+// in the ReplyCount of their parent.
+func (s *commentStore) AttachReplies(ctx context.Context, roots []*Comment, depth int) error {
+	if depth < 1 || len(roots) == 0 {
+		return nil
+	}
+
+	rootIDs := make([]int64, 0, len(roots))
+	for _, root := range roots {
+		rootIDs = append(rootIDs, root.ID)
+	}
+
+	query := `
+		WITH RECURSIVE thread AS (
+			SELECT replies.*, 1 AS depth
+			FROM unnest($1::bigint[]) AS root (id)
+			CROSS JOIN LATERAL (
+				SELECT * FROM comments
+				WHERE parent_id = root.id
+				ORDER BY created_at, id
+				LIMIT $3
+			) replies
+
+			UNION ALL
+
+			SELECT replies.*, thread.depth + 1
+			FROM thread
+			CROSS JOIN LATERAL (
+				SELECT * FROM comments
+				WHERE parent_id = thread.id
+				ORDER BY created_at, id
+				LIMIT $3
+			) replies
+			WHERE thread.depth < $2
+		)
+		SELECT ` + commentColumns + `
+		FROM thread c
+		JOIN users u ON u.id = c.user_id
+		ORDER BY c.depth, c.created_at, c.id
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, pq.Array(rootIDs), depth, maxRepliesPerParent)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	byID := make(map[int64]*Comment, len(roots))
+	for _, root := range roots {
+		byID[root.ID] = root
+	}
+
+	// Rows come level by level, so a parent is always seen before its replies
+	for rows.Next() {
+		reply, err := scanComment(rows)
+		if err != nil {
+			return err
+		}
+
+		parent := byID[*reply.ParentID]
+		parent.Replies = append(parent.Replies, reply)
+		byID[reply.ID] = reply
+	}
+
+	return rows.Err()
+}
@@ -0,0 +1,84 @@
+package store
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/sangtandoan/social/internal/models/dto"
+)
+
+func TestAttachRepliesCapsEachParent(t *testing.T) {
+	db := testDB(t)
+	s := &commentStore{db}
+	ctx := context.Background()
+
+	userID := createTestUser(t, db)
+	post := createTestPost(t, db, userID, "thread")
+
+	comment := func(parentID *int64) *Comment {
+		t.Helper()
+
+		c := &Comment{PostID: int64(post.ID), UserID: userID, Content: "reply", ParentID: parentID}
+		if err := s.Create(ctx, c); err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	root := comment(nil)
+	var replyIDs []int64
+	for range maxRepliesPerParent + 2 {
+		replyIDs = append(replyIDs, comment(&root.ID).ID)
+	}
+	nested := comment(&replyIDs[0])
+
+	root, err := s.Get(ctx, root.ID, root.PostID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.AttachReplies(ctx, []*Comment{root}, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	// The oldest replies are loaded, the rest only counted
+	if got := commentIDs(root.Replies); !slices.Equal(got, replyIDs[:maxRepliesPerParent]) {
+		t.Fatalf("replies = %v, want %v", got, replyIDs[:maxRepliesPerParent])
+	}
+	if root.ReplyCount != int64(len(replyIDs)) {
+		t.Fatalf("reply count = %d, want %d", root.ReplyCount, len(replyIDs))
+	}
+	if root.Replies[0].ReplyCount != 1 || len(root.Replies[0].Replies) != 1 ||
+		root.Replies[0].Replies[0].ID != nested.ID {
+		t.Fatalf("second level = %+v", root.Replies[0].Replies)
+	}
+
+	// Paging the replies endpoint reaches every one of them
+	var paged []*Comment
+	page := dto.Pagination{Limit: 5}
+	for range len(replyIDs) {
+		replies, next, err := s.ListReplies(ctx, root.ID, &page)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		paged = append(paged, replies...)
+		if next == nil {
+			break
+		}
+		page.After = next
+	}
+
+	if got := commentIDs(paged); !slices.Equal(got, replyIDs) {
+		t.Fatalf("paged replies = %v, want %v", got, replyIDs)
+	}
+}
+
+func commentIDs(comments []*Comment) []int64 {
+	ids := make([]int64, 0, len(comments))
+	for _, comment := range comments {
+		ids = append(ids, comment.ID)
+	}
+	return ids
+}
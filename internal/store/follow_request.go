@@ -0,0 +1,144 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/sangtandoan/social/internal/models/dto"
+	"github.com/sangtandoan/social/internal/utils"
+)
+
+// Pending follows of private accounts live apart from followers, so every
+// visibility check on followers treats them as non-followers
+type followRequestStore struct {
+	db *sql.DB
+}
+
+func NewFollowRequestStore(db *sql.DB) *followRequestStore {
+	return &followRequestStore{db}
+}
+
+// FollowRequest is an account asking to follow the user
+type FollowRequest struct {
+	RequestedAt time.Time `json:"requested_at"`
+	Username    string    `json:"username"`
+	ID          int64     `json:"id"`
+}
+
+// ListIncoming returns a page of the requests to follow the user, latest first
+func (s *followRequestStore) ListIncoming(
+	ctx context.Context,
+	userID int64,
+	page *dto.Pagination,
+) ([]*FollowRequest, *utils.Cursor, error) {
+	query := `
+		SELECT fr.id, fr.username, fr.created_at
+		FROM (
+			SELECT u.id, u.username, r.created_at
+			FROM follow_requests r
+			JOIN users u ON u.id = r.requester_id
+			WHERE r.user_id = $1
+		) fr
+		WHERE ` + afterCursor("fr", 2, 3) + `
+		ORDER BY fr.created_at DESC, fr.id DESC
+		LIMIT $4
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
+	defer cancel()
+
+	createdAt, id := cursorArgs(page.After)
+
+	rows, err := s.db.QueryContext(ctx, query, userID, createdAt, id, page.Limit+1)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	res := []*FollowRequest{}
+	for rows.Next() {
+		var request FollowRequest
+		if err := rows.Scan(&request.ID, &request.Username, &request.RequestedAt); err != nil {
+			return nil, nil, err
+		}
+
+		res = append(res, &request)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	res, next := nextPage(res, page.Limit, func(request *FollowRequest) utils.Cursor {
+		return utils.Cursor{CreatedAt: request.RequestedAt, ID: request.ID}
+	})
+	return res, next, nil
+}
+
+// Approve turns the request of requesterID into a follow of userID
+func (s *followRequestStore) Approve(ctx context.Context, userID, requesterID int64) error {
+	query := `
+		WITH request AS (
+			DELETE FROM follow_requests
+			WHERE user_id = $1 AND requester_id = $2
+			RETURNING user_id, requester_id
+		), followed AS (
+			INSERT INTO followers (user_id, follower_id)
+			SELECT user_id, requester_id FROM request
+			ON CONFLICT DO NOTHING
+		)
+		SELECT EXISTS (SELECT 1 FROM request)
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
+	defer cancel()
+
+	var found bool
+	err := s.db.QueryRowContext(ctx, query, userID, requesterID).Scan(&found)
+	if err != nil {
+		return err
+	}
+
+	if !found {
+		return utils.ErrNotFound
+	}
+
+	return nil
+}
+
+// ApproveAll accepts every pending request of the user, for when the account goes public
+func (s *followRequestStore) ApproveAll(ctx context.Context, userID int64) error {
+	executor := GetExecutor(ctx, s.db)
+	query := `
+		WITH request AS (
+			DELETE FROM follow_requests WHERE user_id = $1
+			RETURNING user_id, requester_id
+		)
+		INSERT INTO followers (user_id, follower_id)
+		SELECT user_id, requester_id FROM request
+		ON CONFLICT DO NOTHING
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
+	defer cancel()
+
+	_, err := executor.ExecContext(ctx, query, userID)
+	return err
+}
+
+// Delete drops the request of requesterID to follow userID, whether the user
+// rejects it or the requester cancels it
+func (s *followRequestStore) Delete(ctx context.Context, userID, requesterID int64) error {
+	query := "DELETE FROM follow_requests WHERE user_id = $1 AND requester_id = $2"
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx, query, userID, requesterID)
+	if err != nil {
+		return err
+	}
+
+	return checkAffected(result)
+}
@@ -3,6 +3,10 @@ package store
 import (
 	"context"
 	"database/sql"
+	"time"
+
+	"github.com/sangtandoan/social/internal/models/dto"
+	"github.com/sangtandoan/social/internal/utils"
 )
 
 type followerStore struct {
@@ -18,15 +22,49 @@ type FollowParams struct {
 	FollowerID int64
 }
 
-func (s *followerStore) Follow(ctx context.Context, arg *FollowParams) error {
-	query := "INSERT INTO followers (user_id, follower_id) VALUES ($1, $2)"
+// Follow makes the follower follow the user, or asks to when the user's account is
+// private. It reports whether the follow is pending the user's approval.
+func (s *followerStore) Follow(ctx context.Context, arg *FollowParams) (bool, error) {
+	query := `
+		WITH target AS (
+			SELECT id, is_private FROM users WHERE id = $1
+		), followed AS (
+			INSERT INTO followers (user_id, follower_id)
+			SELECT id, $2 FROM target WHERE NOT is_private
+			RETURNING 1
+		), requested AS (
+			INSERT INTO follow_requests (user_id, requester_id)
+			SELECT id, $2 FROM target
+			WHERE is_private AND NOT EXISTS (
+				SELECT 1 FROM followers WHERE user_id = $1 AND follower_id = $2
+			)
+			RETURNING 1
+		)
+		SELECT
+			EXISTS (SELECT 1 FROM target),
+			EXISTS (SELECT 1 FROM followed),
+			EXISTS (SELECT 1 FROM requested)
+	`
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
 	defer cancel()
 
-	_, err := s.db.ExecContext(ctx, query, arg.UserID, arg.FollowerID)
+	var found, followed, requested bool
+	err := s.db.QueryRowContext(ctx, query, arg.UserID, arg.FollowerID).
+		Scan(&found, &followed, &requested)
+	if err != nil {
+		return false, err
+	}
+
+	switch {
+	case !found:
+		return false, utils.ErrNotFound
+	case !followed && !requested:
+		// A private account that the follower already follows
+		return false, utils.ErrAlreadyFollowing
+	}
 
-	return err
+	return requested, nil
 }
 
 type UnfollowParams struct {
@@ -40,7 +78,123 @@ func (s *followerStore) Unfollow(ctx context.Context, arg *UnfollowParams) error
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
 	defer cancel()
 
-	_, err := s.db.ExecContext(ctx, query, arg.UserID, arg.FollowerID)
+	result, err := s.db.ExecContext(ctx, query, arg.UserID, arg.FollowerID)
+	if err != nil {
+		return err
+	}
+
+	return checkAffected(result)
+}
+
+// IsFollowing reports whether followerID follows userID
+func (s *followerStore) IsFollowing(ctx context.Context, userID, followerID int64) (bool, error) {
+	query := "SELECT EXISTS (SELECT 1 FROM followers WHERE user_id = $1 AND follower_id = $2)"
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
+	defer cancel()
+
+	var following bool
+	err := s.db.QueryRowContext(ctx, query, userID, followerID).Scan(&following)
+
+	return following, err
+}
+
+type FollowCounts struct {
+	Followers int64 `json:"followers_count"`
+	Following int64 `json:"following_count"`
+}
+
+func (s *followerStore) Counts(ctx context.Context, userID int64) (*FollowCounts, error) {
+	query := `
+		SELECT
+			(SELECT COUNT(*) FROM followers WHERE user_id = $1),
+			(SELECT COUNT(*) FROM followers WHERE follower_id = $1)
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
+	defer cancel()
+
+	var counts FollowCounts
+	err := s.db.QueryRowContext(ctx, query, userID).Scan(&counts.Followers, &counts.Following)
+	if err != nil {
+		return nil, err
+	}
+
+	return &counts, nil
+}
+
+// FollowUser is an account in a follower or following listing
+type FollowUser struct {
+	FollowedAt time.Time `json:"followed_at"`
+	Username   string    `json:"username"`
+	ID         int64     `json:"id"`
+}
+
+// ListFollowers returns a page of the accounts following the user, latest first
+func (s *followerStore) ListFollowers(
+	ctx context.Context,
+	userID int64,
+	page *dto.Pagination,
+) ([]*FollowUser, *utils.Cursor, error) {
+	return s.list(ctx, "f.user_id = $1", "f.follower_id", userID, page)
+}
+
+// ListFollowing returns a page of the accounts the user follows, latest first
+func (s *followerStore) ListFollowing(
+	ctx context.Context,
+	userID int64,
+	page *dto.Pagination,
+) ([]*FollowUser, *utils.Cursor, error) {
+	return s.list(ctx, "f.follower_id = $1", "f.user_id", userID, page)
+}
+
+// list pages through the followers rows matching where, otherColumn is the account to show
+func (s *followerStore) list(
+	ctx context.Context,
+	where, otherColumn string,
+	userID int64,
+	page *dto.Pagination,
+) ([]*FollowUser, *utils.Cursor, error) {
+	query := `
+		SELECT fu.id, fu.username, fu.created_at
+		FROM (
+			SELECT u.id, u.username, f.created_at
+			FROM followers f
+			JOIN users u ON u.id = ` + otherColumn + `
+			WHERE ` + where + `
+		) fu
+		WHERE ` + afterCursor("fu", 2, 3) + `
+		ORDER BY fu.created_at DESC, fu.id DESC
+		LIMIT $4
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
+	defer cancel()
+
+	createdAt, id := cursorArgs(page.After)
+
+	rows, err := s.db.QueryContext(ctx, query, userID, createdAt, id, page.Limit+1)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	res := []*FollowUser{}
+	for rows.Next() {
+		var user FollowUser
+		if err := rows.Scan(&user.ID, &user.Username, &user.FollowedAt); err != nil {
+			return nil, nil, err
+		}
+
+		res = append(res, &user)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
 
-	return err
+	res, next := nextPage(res, page.Limit, func(user *FollowUser) utils.Cursor {
+		return utils.Cursor{CreatedAt: user.FollowedAt, ID: user.ID}
+	})
+	return res, next, nil
 }
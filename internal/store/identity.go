@@ -0,0 +1,44 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/sangtandoan/social/internal/models/params"
+)
+
+type identityStore struct {
+	db *sql.DB
+}
+
+func NewIdentityStore(db *sql.DB) *identityStore {
+	return &identityStore{db}
+}
+
+// GetUserID finds the user linked to an external account
+func (s *identityStore) GetUserID(ctx context.Context, provider, subject string) (int64, error) {
+	executor := GetExecutor(ctx, s.db)
+	query := "SELECT user_id FROM user_identities WHERE provider = $1 AND subject = $2"
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
+	defer cancel()
+
+	var userID int64
+	err := executor.QueryRowContext(ctx, query, provider, subject).Scan(&userID)
+	if err != nil {
+		return -1, err
+	}
+
+	return userID, nil
+}
+
+func (s *identityStore) Create(ctx context.Context, arg *params.CreateIdentityParams) error {
+	executor := GetExecutor(ctx, s.db)
+	query := "INSERT INTO user_identities (user_id, provider, subject, email) VALUES ($1, $2, $3, $4)"
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
+	defer cancel()
+
+	_, err := executor.ExecContext(ctx, query, arg.UserID, arg.Provider, arg.Subject, arg.Email)
+	return err
+}
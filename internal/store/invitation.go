@@ -5,8 +5,11 @@ import (
 	"crypto/sha256"
 	"database/sql"
 	"encoding/hex"
+	"errors"
+	"time"
 
 	"github.com/sangtandoan/social/internal/models/params"
+	"github.com/sangtandoan/social/internal/utils"
 )
 
 type invitationStore struct {
@@ -30,6 +33,7 @@ func (s *invitationStore) CreateInvitation(
 	return err
 }
 
+// GetUserIDFromInvitation only returns the owner of a token that can still be used
 func (s *invitationStore) GetUserIDFromInvitation(
 	ctx context.Context,
 	token string,
@@ -39,15 +43,52 @@ func (s *invitationStore) GetUserIDFromInvitation(
 	hash := sha256.Sum256([]byte(token))
 	hashedToken := hex.EncodeToString(hash[:])
 
-	query := "SELECT user_id FROM invitations WHERE token = $1"
+	query := "SELECT user_id, expires_at, used_at FROM invitations WHERE token = $1 FOR UPDATE"
 
 	row := executor.QueryRowContext(ctx, query, hashedToken)
 
 	var userID int64
-	err := row.Scan(&userID)
+	var expiresAt time.Time
+	var usedAt sql.NullTime
+	err := row.Scan(&userID, &expiresAt, &usedAt)
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return -1, utils.ErrInvitationNotFound
+		}
 		return -1, err
 	}
 
+	if usedAt.Valid {
+		return -1, utils.ErrInvitationUsed
+	}
+
+	if time.Now().After(expiresAt) {
+		return -1, utils.ErrInvitationExpired
+	}
+
 	return userID, nil
 }
+
+func (s *invitationStore) MarkInvitationUsed(ctx context.Context, token string) error {
+	executor := GetExecutor(ctx, s.db)
+
+	hash := sha256.Sum256([]byte(token))
+	hashedToken := hex.EncodeToString(hash[:])
+
+	query := "UPDATE invitations SET used_at = NOW() WHERE token = $1"
+
+	_, err := executor.ExecContext(ctx, query, hashedToken)
+
+	return err
+}
+
+// DeleteUnusedByUserID invalidates every pending token of the user before a new one is issued
+func (s *invitationStore) DeleteUnusedByUserID(ctx context.Context, userID int64) error {
+	executor := GetExecutor(ctx, s.db)
+
+	query := "DELETE FROM invitations WHERE user_id = $1 AND used_at IS NULL"
+
+	_, err := executor.ExecContext(ctx, query, userID)
+
+	return err
+}
@@ -0,0 +1,54 @@
+package store
+
+import (
+	"fmt"
+
+	"github.com/sangtandoan/social/internal/utils"
+)
+
+// afterCursor matches the rows of alias that come after the cursor in
+// (created_at, id) descending order, every row when the cursor is nil
+func afterCursor(alias string, createdAtParam, idParam int) string {
+	return afterCursorOn(alias, "created_at", createdAtParam, idParam)
+}
+
+// afterCursorOn is afterCursor for listings ordered by another timestamp column
+func afterCursorOn(alias, column string, timeParam, idParam int) string {
+	return fmt.Sprintf(
+		"($%[3]d::timestamptz IS NULL OR (%[1]s.%[2]s, %[1]s.id) < ($%[3]d, $%[4]d))",
+		alias,
+		column,
+		timeParam,
+		idParam,
+	)
+}
+
+// afterCursorAsc is afterCursor for listings in (created_at, id) ascending order
+func afterCursorAsc(alias string, createdAtParam, idParam int) string {
+	return fmt.Sprintf(
+		"($%[2]d::timestamptz IS NULL OR (%[1]s.created_at, %[1]s.id) > ($%[2]d, $%[3]d))",
+		alias,
+		createdAtParam,
+		idParam,
+	)
+}
+
+func cursorArgs(after *utils.Cursor) (any, any) {
+	if after == nil {
+		return nil, nil
+	}
+
+	return after.CreatedAt, after.ID
+}
+
+// nextPage drops the extra row a query fetched past limit to tell whether
+// there is a next page, and returns the cursor to it
+func nextPage[T any](rows []T, limit int, position func(T) utils.Cursor) ([]T, *utils.Cursor) {
+	if len(rows) <= limit {
+		return rows, nil
+	}
+
+	rows = rows[:limit]
+	cursor := position(rows[limit-1])
+	return rows, &cursor
+}
@@ -0,0 +1,59 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/sangtandoan/social/internal/models/params"
+)
+
+type passwordResetStore struct {
+	db *sql.DB
+}
+
+func NewPasswordResetStore(db *sql.DB) *passwordResetStore {
+	return &passwordResetStore{db}
+}
+
+func (s *passwordResetStore) CreatePasswordReset(
+	ctx context.Context,
+	arg *params.CreatePasswordResetParams,
+) error {
+	executor := GetExecutor(ctx, s.db)
+
+	query := "INSERT INTO password_resets (user_id, token, expires_at) VALUES ($1, $2, $3)"
+
+	_, err := executor.ExecContext(ctx, query, arg.UserID, arg.Token, arg.ExpiresAt)
+
+	return err
+}
+
+// ConsumePasswordReset deletes the token while reading it, so it can only be used once
+func (s *passwordResetStore) ConsumePasswordReset(
+	ctx context.Context,
+	token string,
+) (int64, error) {
+	executor := GetExecutor(ctx, s.db)
+
+	query := "DELETE FROM password_resets WHERE token = $1 AND expires_at > NOW() RETURNING user_id"
+
+	row := executor.QueryRowContext(ctx, query, token)
+
+	var userID int64
+	err := row.Scan(&userID)
+	if err != nil {
+		return -1, err
+	}
+
+	return userID, nil
+}
+
+func (s *passwordResetStore) DeleteByUserID(ctx context.Context, userID int64) error {
+	executor := GetExecutor(ctx, s.db)
+
+	query := "DELETE FROM password_resets WHERE user_id = $1"
+
+	_, err := executor.ExecContext(ctx, query, userID)
+
+	return err
+}
@@ -0,0 +1,197 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/sangtandoan/social/internal/utils"
+)
+
+type policyStore struct {
+	db *sql.DB
+}
+
+func NewPolicyStore(db *sql.DB) *policyStore {
+	return &policyStore{db}
+}
+
+// PolicyCondition compares an attribute of the request with either a literal
+// Value or, when ValueFrom is set, another attribute of the same request
+type PolicyCondition struct {
+	Value     any    `json:"value"`
+	Attribute string `json:"attribute"`
+	Operator  string `json:"operator"`
+	ValueFrom string `json:"value_from,omitempty"`
+}
+
+type Policy struct {
+	CreatedAt    time.Time         `json:"created_at"`
+	UpdatedAt    time.Time         `json:"updated_at"`
+	Name         string            `json:"name"`
+	Description  string            `json:"description"`
+	Effect       string            `json:"effect"`
+	ResourceType string            `json:"resource_type"`
+	Actions      []string          `json:"actions"`
+	Conditions   []PolicyCondition `json:"conditions"`
+	ID           int64             `json:"id"`
+	Enabled      bool              `json:"enabled"`
+}
+
+const policyColumns = `
+	id, name, description, effect, resource_type, actions, conditions, enabled, created_at, updated_at
+`
+
+func (s *policyStore) List(ctx context.Context) ([]*Policy, error) {
+	return s.list(ctx, "SELECT "+policyColumns+" FROM policies ORDER BY id")
+}
+
+func (s *policyStore) ListEnabled(ctx context.Context) ([]*Policy, error) {
+	return s.list(ctx, "SELECT "+policyColumns+" FROM policies WHERE enabled ORDER BY id")
+}
+
+func (s *policyStore) list(ctx context.Context, query string) ([]*Policy, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := []*Policy{}
+	for rows.Next() {
+		var policy Policy
+		var conditions []byte
+
+		err := rows.Scan(
+			&policy.ID,
+			&policy.Name,
+			&policy.Description,
+			&policy.Effect,
+			&policy.ResourceType,
+			pq.Array(&policy.Actions),
+			&conditions,
+			&policy.Enabled,
+			&policy.CreatedAt,
+			&policy.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := json.Unmarshal(conditions, &policy.Conditions); err != nil {
+			return nil, err
+		}
+
+		res = append(res, &policy)
+	}
+
+	return res, rows.Err()
+}
+
+func (s *policyStore) Create(ctx context.Context, policy *Policy) error {
+	query := `
+		INSERT INTO policies (name, description, effect, resource_type, actions, conditions, enabled)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at, updated_at
+	`
+
+	conditions, err := marshalConditions(policy.Conditions)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
+	defer cancel()
+
+	row := s.db.QueryRowContext(
+		ctx,
+		query,
+		policy.Name,
+		policy.Description,
+		policy.Effect,
+		policy.ResourceType,
+		pq.Array(policy.Actions),
+		conditions,
+		policy.Enabled,
+	)
+
+	return row.Scan(&policy.ID, &policy.CreatedAt, &policy.UpdatedAt)
+}
+
+func (s *policyStore) Update(ctx context.Context, policy *Policy) error {
+	query := `
+		UPDATE policies
+		SET name = $1, description = $2, effect = $3, resource_type = $4, actions = $5,
+			conditions = $6, enabled = $7, updated_at = NOW()
+		WHERE id = $8
+		RETURNING created_at, updated_at
+	`
+
+	conditions, err := marshalConditions(policy.Conditions)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
+	defer cancel()
+
+	row := s.db.QueryRowContext(
+		ctx,
+		query,
+		policy.Name,
+		policy.Description,
+		policy.Effect,
+		policy.ResourceType,
+		pq.Array(policy.Actions),
+		conditions,
+		policy.Enabled,
+		policy.ID,
+	)
+
+	err = row.Scan(&policy.CreatedAt, &policy.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return utils.ErrNotFound
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (s *policyStore) Delete(ctx context.Context, id int64) error {
+	query := "DELETE FROM policies WHERE id = $1"
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return utils.ErrNotFound
+	}
+
+	return nil
+}
+
+func marshalConditions(conditions []PolicyCondition) ([]byte, error) {
+	if conditions == nil {
+		conditions = []PolicyCondition{}
+	}
+
+	return json.Marshal(conditions)
+}
@@ -0,0 +1,149 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/sangtandoan/social/internal/utils"
+)
+
+type postRevisionStore struct {
+	db *sql.DB
+}
+
+func NewPostRevisionStore(db *sql.DB) *postRevisionStore {
+	return &postRevisionStore{db}
+}
+
+// PostRevision is the state of a post after an edit, revision 1 is the post as first published
+type PostRevision struct {
+	CreatedAt time.Time `json:"created_at"`
+	EditedBy  *int64    `json:"edited_by"`
+	Title     string    `json:"title"`
+	Content   string    `json:"content"`
+	Tags      []string  `json:"tags"`
+	PostID    int64     `json:"post_id"`
+	Revision  int       `json:"revision"`
+}
+
+// LockForEdit locks the post until the transaction ends and, the first time the
+// post is edited, records its original state as revision 1. It must run in the
+// same transaction as the update.
+func (s *postRevisionStore) LockForEdit(ctx context.Context, postID int64) error {
+	executor := GetExecutor(ctx, s.db)
+	query := `
+		WITH locked AS (
+			SELECT id, title, content, tags, user_id, created_at
+			FROM posts
+			WHERE id = $1 AND deleted_at IS NULL
+			FOR UPDATE
+		)
+		INSERT INTO post_revisions (post_id, revision, title, content, tags, edited_by, created_at)
+		SELECT id, 1, title, content, tags, user_id, created_at
+		FROM locked
+		WHERE NOT EXISTS (SELECT 1 FROM post_revisions WHERE post_id = $1)
+		ON CONFLICT (post_id, revision) DO NOTHING
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
+	defer cancel()
+
+	_, err := executor.ExecContext(ctx, query, postID)
+	return err
+}
+
+// Create records the post as it is after an edit by editedBy, the revision is the post's version
+func (s *postRevisionStore) Create(ctx context.Context, post *Post, editedBy int64) error {
+	executor := GetExecutor(ctx, s.db)
+	query := `
+		INSERT INTO post_revisions (post_id, revision, title, content, tags, edited_by)
+		VALUES ($1, $6, $2, $3, $4, $5)
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
+	defer cancel()
+
+	_, err := executor.ExecContext(
+		ctx,
+		query,
+		post.ID,
+		post.Title,
+		post.Content,
+		pq.Array(post.Tags),
+		editedBy,
+		post.Version,
+	)
+	return err
+}
+
+func (s *postRevisionStore) ListByPostID(ctx context.Context, postID int64) ([]*PostRevision, error) {
+	query := `
+		SELECT post_id, revision, title, content, tags, edited_by, created_at
+		FROM post_revisions
+		WHERE post_id = $1
+		ORDER BY revision
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, postID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := []*PostRevision{}
+	for rows.Next() {
+		revision, err := scanPostRevision(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		res = append(res, revision)
+	}
+
+	return res, rows.Err()
+}
+
+func (s *postRevisionStore) Get(ctx context.Context, postID int64, revision int) (*PostRevision, error) {
+	query := `
+		SELECT post_id, revision, title, content, tags, edited_by, created_at
+		FROM post_revisions
+		WHERE post_id = $1 AND revision = $2
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
+	defer cancel()
+
+	res, err := scanPostRevision(s.db.QueryRowContext(ctx, query, postID, revision))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, utils.ErrNotFound
+		}
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func scanPostRevision(row rowScanner) (*PostRevision, error) {
+	var revision PostRevision
+	err := row.Scan(
+		&revision.PostID,
+		&revision.Revision,
+		&revision.Title,
+		&revision.Content,
+		pq.Array(&revision.Tags),
+		&revision.EditedBy,
+		&revision.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &revision, nil
+}
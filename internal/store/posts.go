@@ -17,18 +17,83 @@ type PostsStore struct {
 	db *sql.DB
 }
 
+const (
+	PostStatusDraft     = "draft"
+	PostStatusScheduled = "scheduled"
+	PostStatusPublished = "published"
+)
+
+const (
+	PostVisibilityPublic    = "public"
+	PostVisibilityFollowers = "followers"
+	PostVisibilityMentioned = "mentioned"
+	PostVisibilityPrivate   = "private"
+)
+
 type Post struct {
 	Title     string    `json:"title"`
 	Content   string    `json:"content"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
-	Tags      []string  `json:"tags"`
-	UserID    int       `json:"user_id"`
-	ID        int       `json:"id"`
+	// EditedAt is set once the post has been changed after it was published
+	EditedAt *time.Time `json:"edited_at"`
+	// PublishAt is when the post was or will be published, nil for drafts
+	PublishAt *time.Time `json:"publish_at"`
+	Status    string     `json:"status"`
+	// Visibility is who besides the author can read the post
+	Visibility     string         `json:"visibility"`
+	ReactionCounts ReactionCounts `json:"reaction_counts"`
+	Tags           []string       `json:"tags"`
+	UserID         int            `json:"user_id"`
+	ID             int            `json:"id"`
+	// Version grows by one with every edit of the content, reactions leave it as is
+	Version int64 `json:"version"`
+}
+
+const postColumns = "id, user_id, title, content, tags, created_at, updated_at, edited_at, version, " +
+	"status, publish_at, visibility, reaction_counts"
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanPost(row rowScanner) (*Post, error) {
+	var post Post
+	err := row.Scan(
+		&post.ID,
+		&post.UserID,
+		&post.Title,
+		&post.Content,
+		pq.Array(&post.Tags),
+		&post.CreatedAt,
+		&post.UpdatedAt,
+		&post.EditedAt,
+		&post.Version,
+		&post.Status,
+		&post.PublishAt,
+		&post.Visibility,
+		&post.ReactionCounts,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &post, nil
 }
 
+// Create inserts the post together with the users it mentions
 func (s *PostsStore) Create(ctx context.Context, post *Post) error {
-	query := "INSERT INTO posts (title, content, user_id, tags) VALUES ($1, $2, $3, $4) RETURNING id, created_at, updated_at"
+	query := `
+		WITH post AS (
+			INSERT INTO posts (title, content, user_id, tags, status, publish_at, visibility)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+			RETURNING id, created_at, updated_at, version
+		), mentions AS (
+			INSERT INTO post_mentions (post_id, user_id)
+			SELECT post.id, u.id FROM post, users u WHERE u.username = ANY($8)
+		)
+		SELECT id, created_at, updated_at, version FROM post
+	`
 
 	// SQL query timeout
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
@@ -41,10 +106,14 @@ func (s *PostsStore) Create(ctx context.Context, post *Post) error {
 		post.Content,
 		post.UserID,
 		pq.Array(post.Tags),
+		post.Status,
+		post.PublishAt,
+		post.Visibility,
+		pq.Array(utils.ExtractMentions(post.Content)),
 	)
 
 	// Scan need address of fields in that struct not address of that struct
-	err := row.Scan(&post.ID, &post.CreatedAt, &post.UpdatedAt)
+	err := row.Scan(&post.ID, &post.CreatedAt, &post.UpdatedAt, &post.Version)
 	if err != nil {
 		return err
 	}
@@ -52,19 +121,13 @@ func (s *PostsStore) Create(ctx context.Context, post *Post) error {
 }
 
 func (s *PostsStore) GetByID(ctx context.Context, id int64) (*Post, error) {
-	query := "SELECT id, title, content, tags, created_at, updated_at FROM posts WHERE id = $1"
+	executor := GetExecutor(ctx, s.db)
+	query := "SELECT " + postColumns + " FROM posts WHERE id = $1 AND deleted_at IS NULL"
 
-	var post Post
-	row := s.db.QueryRowContext(ctx, query, id)
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
+	defer cancel()
 
-	err := row.Scan(
-		&post.ID,
-		&post.Title,
-		&post.Content,
-		pq.Array(&post.Tags),
-		&post.CreatedAt,
-		&post.UpdatedAt,
-	)
+	post, err := scanPost(executor.QueryRowContext(ctx, query, id))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, utils.ErrNotFound
@@ -72,55 +135,140 @@ func (s *PostsStore) GetByID(ctx context.Context, id int64) (*Post, error) {
 		return nil, err
 	}
 
-	return &post, nil
+	return post, nil
 }
 
-func (s *PostsStore) GetAll(ctx context.Context) ([]*Post, error) {
-	query := "SELECT id, title, content, tags, created_at, updated_at FROM posts"
+// likeEscaper makes user input match literally inside an ILIKE pattern
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// visibleTo matches the posts p the viewer may read, a viewer of 0 is anonymous.
+// Whatever their visibility, the posts of a private account are for its followers only.
+func visibleTo(viewerParam int) string {
+	return fmt.Sprintf(`(
+		p.user_id = $%[1]d OR (
+			(
+				p.visibility = 'public' OR
+				(p.visibility = 'followers' AND %[2]s) OR
+				(p.visibility = 'mentioned' AND EXISTS (
+					SELECT 1 FROM post_mentions m WHERE m.post_id = p.id AND m.user_id = $%[1]d
+				))
+			) AND (
+				NOT (SELECT author.is_private FROM users author WHERE author.id = p.user_id) OR
+				%[2]s
+			)
+		)
+	)`, viewerParam, followsAuthor(viewerParam))
+}
+
+// followsAuthor matches the posts p whose author the viewer follows, a pending
+// follow request does not count
+func followsAuthor(viewerParam int) string {
+	return fmt.Sprintf(
+		"EXISTS (SELECT 1 FROM followers f WHERE f.user_id = p.user_id AND f.follower_id = $%d)",
+		viewerParam,
+	)
+}
+
+// IsVisibleTo reports whether the viewer may read the post given its visibility
+// and whether its author's account is private. It answers from the current row,
+// so a cached copy of a post since deleted or unpublished is not visible either.
+func (s *PostsStore) IsVisibleTo(ctx context.Context, postID, viewerID int64) (bool, error) {
+	query := "SELECT EXISTS (SELECT 1 FROM posts p WHERE p.id = $1 AND " +
+		"p.deleted_at IS NULL AND p.status = 'published' AND " + visibleTo(2) + ")"
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
 	defer cancel()
 
-	var res []*Post
-	rows, err := s.db.QueryContext(ctx, query)
+	var visible bool
+	err := s.db.QueryRowContext(ctx, query, postID, viewerID).Scan(&visible)
+	return visible, err
+}
+
+// GetAll returns a page of the published posts the viewer may read, most recently
+// published first
+func (s *PostsStore) GetAll(
+	ctx context.Context,
+	viewerID int64,
+	page *dto.Pagination,
+) ([]*Post, *utils.Cursor, error) {
+	query := "SELECT " + postColumns + " FROM posts p " +
+		"WHERE deleted_at IS NULL AND status = 'published' AND " + visibleTo(1) +
+		" AND " + afterCursorOn("p", "publish_at", 2, 3) +
+		" ORDER BY publish_at DESC, id DESC LIMIT $4"
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
+	defer cancel()
+
+	createdAt, id := cursorArgs(page.After)
+
+	// One row more than the page tells whether there is a next one
+	rows, err := s.db.QueryContext(ctx, query, viewerID, createdAt, id, page.Limit+1)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
+	defer rows.Close()
 
+	res := []*Post{}
 	for rows.Next() {
-		var post Post
-		var tags pq.StringArray
-
-		err := rows.Scan(
-			&post.ID,
-			&post.Title,
-			&post.Content,
-			&tags,
-			&post.CreatedAt,
-			&post.UpdatedAt,
-		)
+		post, err := scanPost(rows)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 
-		post.Tags = []string(tags)
+		res = append(res, post)
+	}
 
-		res = append(res, &post)
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
 	}
 
-	return res, nil
+	res, next := nextPage(res, page.Limit, postPosition)
+	return res, next, nil
+}
+
+// postPosition places a published post by when it went out, a draft written long
+// before it was published, or a scheduled post, must not land pages back
+func postPosition(post *Post) utils.Cursor {
+	return utils.Cursor{CreatedAt: *post.PublishAt, ID: int64(post.ID)}
 }
 
 type UpdatePostParams struct {
 	Title   *string   `json:"title,omitempty"`
 	Content *string   `json:"content,omitempty"`
 	Tags    *[]string `json:"tags,omitempty"`
-	ID      int64     `json:"id,omitempty"`
+	Status  *string   `json:"status,omitempty"`
+	// Visibility is one of the PostVisibility values
+	Visibility *string `json:"visibility,omitempty"`
+	// PublishAt goes with Status: the time of a scheduled post, nil otherwise
+	PublishAt *time.Time `json:"publish_at,omitempty"`
+	ID        int64      `json:"id,omitempty"`
+	// UserID is the caller, only the owner or a moderator can update the post
+	UserID int64 `json:"-"`
+	// Versions the caller expects the post to be at, from If-Match. Empty matches any.
+	Versions []int64 `json:"-"`
+}
+
+// IsEmpty reports whether the update changes no field of the post
+func (p *UpdatePostParams) IsEmpty() bool {
+	return p.Title == nil && p.Content == nil && p.Tags == nil && p.Status == nil &&
+		p.Visibility == nil
+}
+
+// ownerOrPermission matches the posts the user owns, or every post when the user's
+// role grants the permission. Checking in the statement itself means a post can't
+// change owner, nor the user lose the role, between the check and the write.
+func ownerOrPermission(userParam, permissionParam int) string {
+	return fmt.Sprintf(
+		"(user_id = $%d OR %s)",
+		userParam,
+		userHasPermission(userParam, permissionParam),
+	)
 }
 
 // Parial update should have dynamic query string to optimize query
 // Instead of just update everything, just update needed things will reduce I/O operations
 func (s *PostsStore) UpdatePost(ctx context.Context, arg *UpdatePostParams) (*Post, error) {
+	executor := GetExecutor(ctx, s.db)
 	query := "UPDATE posts SET "
 	var params []any
 	if arg.Title != nil {
@@ -128,7 +276,6 @@ func (s *PostsStore) UpdatePost(ctx context.Context, arg *UpdatePostParams) (*Po
 		params = append(params, arg.Title)
 	}
 	if arg.Content != nil {
-		fmt.Println("I'm at content")
 		query += fmt.Sprintf("content = $%d, ", len(params)+1)
 		params = append(params, arg.Content)
 	}
@@ -137,43 +284,224 @@ func (s *PostsStore) UpdatePost(ctx context.Context, arg *UpdatePostParams) (*Po
 		params = append(params, pq.Array(arg.Tags))
 	}
 
-	query = strings.Trim(query, " ")
-	query = strings.Trim(query, ",")
+	if arg.Status != nil {
+		query += fmt.Sprintf("status = $%d, ", len(params)+1)
+		// Publishing a post that is already published keeps its original date
+		query += fmt.Sprintf(
+			"publish_at = CASE WHEN status = 'published' AND $%d = 'published' THEN publish_at ELSE $%d END, ",
+			len(params)+1,
+			len(params)+2,
+		)
+		params = append(params, arg.Status, arg.PublishAt)
+	}
+	if arg.Visibility != nil {
+		query += fmt.Sprintf("visibility = $%d, ", len(params)+1)
+		params = append(params, arg.Visibility)
+	}
+
+	if len(params) == 0 {
+		return nil, ErrNothingToUpdate
+	}
+
+	// Only changes to a post readers have seen mark it as edited
+	query += "updated_at = NOW(), edited_at = CASE WHEN status = 'published' THEN NOW() ELSE edited_at END, "
+	query += "version = version + 1"
 	query += fmt.Sprintf(
-		" WHERE id = $%d RETURNING id, title, content, tags, created_at, updated_at",
+		" WHERE id = $%d AND deleted_at IS NULL AND %s AND (cardinality($%d::bigint[]) = 0 OR version = ANY($%d))",
 		len(params)+1,
+		ownerOrPermission(len(params)+2, len(params)+3),
+		len(params)+4,
+		len(params)+4,
 	)
-	params = append(params, arg.ID)
+	query += " RETURNING " + postColumns
+
+	// A nil slice would be sent as NULL, which no version matches
+	versions := arg.Versions
+	if versions == nil {
+		versions = []int64{}
+	}
+	params = append(params, arg.ID, arg.UserID, string(PermPostUpdateAny), pq.Array(versions))
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
 	defer cancel()
 
-	row := s.db.QueryRowContext(ctx, query, params...)
-	var post Post
+	post, err := scanPost(executor.QueryRowContext(ctx, query, params...))
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
 
-	err := row.Scan(
-		&post.ID,
-		&post.Title,
-		&post.Content,
-		pq.Array(&post.Tags),
-		&post.CreatedAt,
-		&post.UpdatedAt,
+		if len(arg.Versions) == 0 {
+			// Someone else's post is reported like a missing one
+			return nil, utils.ErrNotFound
+		}
+
+		return nil, s.versionMismatchOrNotFound(ctx, executor, arg)
+	}
+
+	if arg.Content != nil {
+		err = s.replaceMentions(ctx, executor, post)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return post, nil
+}
+
+// replaceMentions records who the post mentions now that its content changed
+func (s *PostsStore) replaceMentions(ctx context.Context, executor Executor, post *Post) error {
+	_, err := executor.ExecContext(ctx, "DELETE FROM post_mentions WHERE post_id = $1", post.ID)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO post_mentions (post_id, user_id)
+		SELECT $1, id FROM users WHERE username = ANY($2)
+	`
+	_, err = executor.ExecContext(ctx, query, post.ID, pq.Array(utils.ExtractMentions(post.Content)))
+	return err
+}
+
+// versionMismatchOrNotFound tells apart why a conditional update matched nothing,
+// only to callers allowed to update the post
+func (s *PostsStore) versionMismatchOrNotFound(
+	ctx context.Context,
+	executor Executor,
+	arg *UpdatePostParams,
+) error {
+	query := "SELECT EXISTS (SELECT 1 FROM posts WHERE id = $1 AND deleted_at IS NULL AND " +
+		ownerOrPermission(2, 3) + ")"
+
+	var exists bool
+	err := executor.QueryRowContext(ctx, query, arg.ID, arg.UserID, string(PermPostUpdateAny)).
+		Scan(&exists)
+	if err != nil {
+		return err
+	}
+
+	if exists {
+		return utils.ErrPreconditionFailed
+	}
+
+	return utils.ErrNotFound
+}
+
+// PostRestoreWindow is how long a deleted post can be restored before it is purged
+const PostRestoreWindow = 30 * 24 * time.Hour
+
+// Delete only hides the post, when userID owns it or may delete any post
+func (s *PostsStore) Delete(ctx context.Context, id, userID int64) error {
+	query := `
+		UPDATE posts SET deleted_at = NOW()
+		WHERE id = $1 AND deleted_at IS NULL AND ` + ownerOrPermission(2, 3)
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx, query, id, userID, string(PermPostDeleteAny))
+	if err != nil {
+		return err
+	}
+
+	return checkAffected(result)
+}
+
+// Restore brings back a post deleted less than PostRestoreWindow ago
+func (s *PostsStore) Restore(ctx context.Context, id, userID int64) error {
+	query := `
+		UPDATE posts SET deleted_at = NULL
+		WHERE id = $1 AND deleted_at > $4 AND ` + ownerOrPermission(2, 3)
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
+	defer cancel()
+
+	result, err := s.db.ExecContext(
+		ctx,
+		query,
+		id,
+		userID,
+		string(PermPostDeleteAny),
+		time.Now().Add(-PostRestoreWindow),
 	)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	return &post, nil
+	return checkAffected(result)
+}
+
+// PurgeDeleted removes up to limit posts deleted before the given time, and their comments
+func (s *PostsStore) PurgeDeleted(ctx context.Context, before time.Time, limit int) (int64, error) {
+	query := `
+		DELETE FROM posts
+		WHERE id IN (
+			SELECT id FROM posts
+			WHERE deleted_at < $1
+			LIMIT $2
+		)
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx, query, before, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
 }
 
-func (s *PostsStore) DeleteByID(ctx context.Context, id int64) error {
-	query := "DELETE FROM posts WHERE id = $1"
+// PublishDue publishes up to limit scheduled posts whose time has come. Rows another
+// replica is publishing are skipped rather than waited for, so any number can run it.
+func (s *PostsStore) PublishDue(ctx context.Context, limit int) ([]int64, error) {
+	query := `
+		UPDATE posts SET status = 'published', updated_at = NOW(), version = version + 1
+		WHERE id IN (
+			SELECT id FROM posts
+			WHERE status = 'scheduled' AND publish_at <= NOW() AND deleted_at IS NULL
+			ORDER BY publish_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id
+	`
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
 	defer cancel()
 
-	_, err := s.db.ExecContext(ctx, query, id)
-	return err
+	rows, err := s.db.QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+// Someone else's post or comment is reported like a missing one
+func checkAffected(result sql.Result) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return utils.ErrNotFound
+	}
+
+	return nil
 }
 
 type PostResponse struct {
@@ -182,38 +510,62 @@ type PostResponse struct {
 	CommentsCount int64
 }
 
+// GetUserFeed returns a page of the user's own posts and the posts of the accounts
+// they follow, most recently published first. Search matches the title or content, tags must all be present.
 func (s *PostsStore) GetUserFeed(
 	ctx context.Context,
 	arg *dto.UserFeedRequest,
-) ([]*PostResponse, error) {
+) ([]*PostResponse, *utils.Cursor, error) {
 	query := `
-		SELECT 
-			p.id, p.user_id, p.title, p.content, p.created_at, p.tags,
-			u.username,
-			COUNT(c.id) as comments_count
-		LEFT JOIN comments c ON c.post_id = p.id
-		LEFT JOIN users u ON u.id = p.user_id
-		JOIN followers f ON f.follower_id = p.user_id OR p.user_id = $1
-		WHERE f.user_id = $1 AND
-			(p.title ILIKE '%' || $4 || '%' OR p.content ILIKE '%' || $4 || '%') AND
-			(p.tags @> $5 OR $5 = '{}')
-		GROUP BY p.id, u.username
-		ORDER BY p.created_at DESC
-		OFFSET $2
-		LIMIT $3
+		SELECT
+			p.id, p.user_id, p.title, p.content, p.created_at, p.edited_at, p.publish_at, p.tags,
+			p.reaction_counts, u.username,
+			(SELECT COUNT(*) FROM comments c WHERE c.post_id = p.id) AS comments_count
+		FROM posts p
+		JOIN users u ON u.id = p.user_id
+		WHERE p.user_id IN (
+				SELECT $1::bigint
+				UNION ALL
+				SELECT f.user_id FROM followers f WHERE f.follower_id = $1
+			) AND
+			p.deleted_at IS NULL AND p.status = 'published' AND ` + visibleTo(1) + ` AND
+			($2 = '' OR p.title ILIKE '%' || $2 || '%' OR p.content ILIKE '%' || $2 || '%') AND
+			(cardinality($3::varchar[]) = 0 OR p.tags @> $3::varchar[]) AND
+			` + afterCursorOn("p", "publish_at", 4, 5) + `
+		ORDER BY p.publish_at DESC, p.id DESC
+		LIMIT $6
 	`
+
+	search := ""
+	if arg.Search != nil {
+		search = likeEscaper.Replace(*arg.Search)
+	}
+
+	// A nil array is sent as NULL, an empty one as {}
+	tags := []string{}
+	if arg.Tags != nil {
+		tags = *arg.Tags
+	}
+
+	createdAt, id := cursorArgs(arg.After)
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
+	defer cancel()
+
 	rows, err := s.db.QueryContext(
 		ctx,
 		query,
 		arg.ID,
-		arg.Offset,
-		arg.Limit,
-		arg.Search,
-		pq.Array(arg.Tags),
+		search,
+		pq.Array(tags),
+		createdAt,
+		id,
+		arg.Limit+1,
 	)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
+	defer rows.Close()
 
 	var arr []*PostResponse
 	for rows.Next() {
@@ -225,16 +577,26 @@ func (s *PostsStore) GetUserFeed(
 			&response.Title,
 			&response.Content,
 			&response.CreatedAt,
+			&response.EditedAt,
+			&response.PublishAt,
 			pq.Array(&response.Tags),
+			&response.ReactionCounts,
 			&response.Username,
 			&response.CommentsCount,
 		)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 
 		arr = append(arr, &response)
 	}
 
-	return arr, nil
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	arr, next := nextPage(arr, arg.Limit, func(response *PostResponse) utils.Cursor {
+		return postPosition(&response.Post)
+	})
+	return arr, next, nil
 }
@@ -0,0 +1,283 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/sangtandoan/social/internal/models/dto"
+	"github.com/sangtandoan/social/internal/utils"
+)
+
+func TestUpdatePostVersions(t *testing.T) {
+	db := testDB(t)
+	s := &PostsStore{db}
+	ctx := context.Background()
+
+	userID := createTestUser(t, db)
+	post := createTestPost(t, db, userID, "first")
+
+	title := "second"
+	updated, err := s.UpdatePost(ctx, &UpdatePostParams{Title: &title, ID: int64(post.ID), UserID: userID})
+	if err != nil {
+		t.Fatalf("update without If-Match: %v", err)
+	}
+	if updated.Version != post.Version+1 {
+		t.Fatalf("version = %d, want %d", updated.Version, post.Version+1)
+	}
+
+	title = "third"
+	_, err = s.UpdatePost(ctx, &UpdatePostParams{
+		Title:    &title,
+		ID:       int64(post.ID),
+		UserID:   userID,
+		Versions: []int64{post.Version},
+	})
+	if !errors.Is(err, utils.ErrPreconditionFailed) {
+		t.Fatalf("update with stale If-Match: err = %v, want %v", err, utils.ErrPreconditionFailed)
+	}
+
+	_, err = s.UpdatePost(ctx, &UpdatePostParams{
+		Title:    &title,
+		ID:       int64(post.ID),
+		UserID:   userID,
+		Versions: []int64{updated.Version},
+	})
+	if err != nil {
+		t.Fatalf("update with current If-Match: %v", err)
+	}
+}
+
+func TestGetUserFeed(t *testing.T) {
+	db := testDB(t)
+	s := &PostsStore{db}
+	ctx := context.Background()
+
+	viewer := createTestUser(t, db)
+	followed := createTestUser(t, db)
+	follower := createTestUser(t, db)
+
+	// The viewer follows one account and is followed back by neither
+	for _, f := range [][2]int64{{followed, viewer}, {viewer, follower}} {
+		_, err := db.Exec("INSERT INTO followers (user_id, follower_id) VALUES ($1, $2)", f[0], f[1])
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	createTestPost(t, db, viewer, "own post", "go")
+	createTestPost(t, db, followed, "followed post")
+	createTestPost(t, db, followed, "followed post tagged", "go", "sql")
+	createTestPost(t, db, follower, "follower post", "go")
+
+	feed := func(arg *dto.UserFeedRequest) []*PostResponse {
+		t.Helper()
+
+		arg.ID = viewer
+		if arg.Limit == 0 {
+			arg.Limit = 20
+		}
+
+		posts, _, err := s.GetUserFeed(ctx, arg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return posts
+	}
+
+	// Without search nor tags the feed is every post of the viewer and of whom they
+	// follow, each once
+	all := feed(&dto.UserFeedRequest{})
+	if got := feedTitles(all); !slices.Equal(got, []string{
+		"followed post tagged",
+		"followed post",
+		"own post",
+	}) {
+		t.Fatalf("feed = %v", got)
+	}
+
+	search := "tagged"
+	if got := feedTitles(feed(&dto.UserFeedRequest{Search: &search})); !slices.Equal(
+		got,
+		[]string{"followed post tagged"},
+	) {
+		t.Fatalf("feed searching %q = %v", search, got)
+	}
+
+	tags := []string{"go"}
+	if got := feedTitles(feed(&dto.UserFeedRequest{Tags: &tags})); !slices.Equal(got, []string{
+		"followed post tagged",
+		"own post",
+	}) {
+		t.Fatalf("feed tagged %v = %v", tags, got)
+	}
+
+	tags = []string{"go", "sql"}
+	if got := feedTitles(feed(&dto.UserFeedRequest{Tags: &tags})); !slices.Equal(
+		got,
+		[]string{"followed post tagged"},
+	) {
+		t.Fatalf("feed tagged %v = %v", tags, got)
+	}
+
+	// The posts share a publish_at second, the cursor must still walk them in order
+	var paged []*PostResponse
+	page := dto.Pagination{Limit: 1}
+	for range len(all) + 1 {
+		posts, next, err := s.GetUserFeed(ctx, &dto.UserFeedRequest{Pagination: page, ID: viewer})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		paged = append(paged, posts...)
+		if next == nil {
+			break
+		}
+		page.After = next
+	}
+
+	if got, want := feedIDs(paged), feedIDs(all); !slices.Equal(got, want) {
+		t.Fatalf("paged feed = %v, want %v", got, want)
+	}
+}
+
+func TestGetUserFeedOrdersByPublishTime(t *testing.T) {
+	db := testDB(t)
+	s := &PostsStore{db}
+	ctx := context.Background()
+
+	viewer := createTestUser(t, db)
+
+	// A draft written a day ago and published now goes above a post that went out an hour ago
+	draft := createTestPost(t, db, viewer, "published draft")
+	recent := createTestPost(t, db, viewer, "recent post")
+	_, err := db.Exec("UPDATE posts SET created_at = NOW() - interval '1 day' WHERE id = $1", draft.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Exec("UPDATE posts SET publish_at = NOW() - interval '1 hour' WHERE id = $1", recent.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var paged []*PostResponse
+	page := dto.Pagination{Limit: 1}
+	for range 3 {
+		posts, next, err := s.GetUserFeed(ctx, &dto.UserFeedRequest{Pagination: page, ID: viewer})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		paged = append(paged, posts...)
+		if next == nil {
+			break
+		}
+		page.After = next
+	}
+
+	if got := feedTitles(paged); !slices.Equal(got, []string{"published draft", "recent post"}) {
+		t.Fatalf("feed = %v", got)
+	}
+}
+
+func feedTitles(posts []*PostResponse) []string {
+	titles := make([]string, 0, len(posts))
+	for _, post := range posts {
+		titles = append(titles, post.Title)
+	}
+	return titles
+}
+
+func feedIDs(posts []*PostResponse) []int {
+	ids := make([]int, 0, len(posts))
+	for _, post := range posts {
+		ids = append(ids, post.ID)
+	}
+	return ids
+}
+
+func TestIsVisibleToPrivateAuthor(t *testing.T) {
+	db := testDB(t)
+	s := &PostsStore{db}
+	ctx := context.Background()
+
+	author := createTestUser(t, db)
+	follower := createTestUser(t, db)
+	requester := createTestUser(t, db)
+	stranger := createTestUser(t, db)
+
+	post := createTestPost(t, db, author, "private author")
+
+	_, err := db.Exec("UPDATE users SET is_private = true WHERE id = $1", author)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = db.Exec("INSERT INTO followers (user_id, follower_id) VALUES ($1, $2)", author, follower)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = db.Exec(
+		"INSERT INTO follow_requests (user_id, requester_id) VALUES ($1, $2)",
+		author,
+		requester,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		name    string
+		viewer  int64
+		visible bool
+	}{
+		{"author", author, true},
+		{"follower", follower, true},
+		{"pending requester", requester, false},
+		{"stranger", stranger, false},
+		{"anonymous", 0, false},
+	} {
+		visible, err := s.IsVisibleTo(ctx, int64(post.ID), tt.viewer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if visible != tt.visible {
+			t.Errorf("%s: visible = %v, want %v", tt.name, visible, tt.visible)
+		}
+	}
+}
+
+func TestIsVisibleToUnpublished(t *testing.T) {
+	db := testDB(t)
+	s := &PostsStore{db}
+	ctx := context.Background()
+
+	author := createTestUser(t, db)
+	viewer := createTestUser(t, db)
+
+	// The cache may still hold these posts as they were when published
+	draft := createTestPost(t, db, author, "unpublished")
+	_, err := db.Exec("UPDATE posts SET status = 'draft', publish_at = NULL WHERE id = $1", draft.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deleted := createTestPost(t, db, author, "deleted")
+	if err := s.Delete(ctx, int64(deleted.ID), author); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, post := range []*Post{draft, deleted} {
+		for _, viewerID := range []int64{viewer, 0} {
+			visible, err := s.IsVisibleTo(ctx, int64(post.ID), viewerID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if visible {
+				t.Errorf("%s post is visible to %d", post.Title, viewerID)
+			}
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// ReactionKinds are the reactions a user can leave on a post or a comment
+var ReactionKinds = []string{"like", "love", "haha", "wow", "sad", "angry"}
+
+func IsReactionKind(kind string) bool {
+	return slices.Contains(ReactionKinds, kind)
+}
+
+// ReactionCounts is the number of reactions of each kind, kinds nobody used are left out
+type ReactionCounts map[string]int64
+
+func (r *ReactionCounts) Scan(src any) error {
+	var data []byte
+	switch v := src.(type) {
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	case nil:
+		*r = ReactionCounts{}
+		return nil
+	default:
+		return fmt.Errorf("can not scan %T into ReactionCounts", src)
+	}
+
+	counts := ReactionCounts{}
+	if err := json.Unmarshal(data, &counts); err != nil {
+		return err
+	}
+
+	*r = counts
+	return nil
+}
+
+// Value is sent as text, lib/pq would send []byte as bytea
+func (r ReactionCounts) Value() (driver.Value, error) {
+	if r == nil {
+		return "{}", nil
+	}
+
+	data, err := json.Marshal(r)
+	return string(data), err
+}
+
+// ReactionTarget is what can be reacted to, the tables are fixed so they can be
+// put in statements safely
+type ReactionTarget struct {
+	table     string
+	reactions string
+	column    string
+}
+
+var (
+	ReactionTargetPost    = ReactionTarget{"posts", "post_reactions", "post_id"}
+	ReactionTargetComment = ReactionTarget{"comments", "comment_reactions", "comment_id"}
+)
+
+type reactionStore struct {
+	db *sql.DB
+}
+
+func NewReactionStore(db *sql.DB) *reactionStore {
+	return &reactionStore{db}
+}
+
+// Toggle leaves the user's reaction of kind on the target, replacing any other kind.
+// Toggling the kind the user already left removes it. It returns the user's reaction
+// afterwards, empty when there is none, and the target's new counts.
+// It must run in a transaction, so the counts change together with the reaction.
+func (s *reactionStore) Toggle(
+	ctx context.Context,
+	target ReactionTarget,
+	targetID, userID int64,
+	kind string,
+) (string, ReactionCounts, error) {
+	executor := GetExecutor(ctx, s.db)
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
+	defer cancel()
+
+	deleteQuery := fmt.Sprintf(
+		"DELETE FROM %s WHERE %s = $1 AND user_id = $2 RETURNING kind",
+		target.reactions,
+		target.column,
+	)
+
+	var previous string
+	err := executor.QueryRowContext(ctx, deleteQuery, targetID, userID).Scan(&previous)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return "", nil, err
+	}
+
+	current := ""
+	if previous != kind {
+		insertQuery := fmt.Sprintf(
+			"INSERT INTO %s (%s, user_id, kind) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING",
+			target.reactions,
+			target.column,
+		)
+
+		result, err := executor.ExecContext(ctx, insertQuery, targetID, userID, kind)
+		if err != nil {
+			return "", nil, err
+		}
+
+		// A concurrent toggle of the same user got there first, its reaction stands
+		if affected, err := result.RowsAffected(); err != nil {
+			return "", nil, err
+		} else if affected == 1 {
+			current = kind
+		}
+	}
+
+	// The row lock orders concurrent toggles on the target until the transaction ends
+	selectQuery := fmt.Sprintf(
+		"SELECT reaction_counts FROM %s WHERE id = $1 FOR UPDATE",
+		target.table,
+	)
+
+	var counts ReactionCounts
+	err = executor.QueryRowContext(ctx, selectQuery, targetID).Scan(&counts)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if previous != "" {
+		counts[previous]--
+		if counts[previous] <= 0 {
+			delete(counts, previous)
+		}
+	}
+	if current != "" {
+		counts[current]++
+	}
+
+	updateQuery := fmt.Sprintf("UPDATE %s SET reaction_counts = $2 WHERE id = $1", target.table)
+
+	_, err = executor.ExecContext(ctx, updateQuery, targetID, counts)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return current, counts, nil
+}
+
+// Reconcile recounts the reactions of every target whose counts drifted, e.g.
+// because the account that reacted was deleted. It returns the ids it fixed.
+// A toggle committed while it runs can be overwritten, the next run corrects it.
+func (s *reactionStore) Reconcile(ctx context.Context, target ReactionTarget) ([]int64, error) {
+	query := fmt.Sprintf(`
+		WITH actual AS (
+			SELECT t.id, COALESCE(
+				(
+					SELECT jsonb_object_agg(kind, n)
+					FROM (
+						SELECT kind, COUNT(*) AS n FROM %[2]s r WHERE r.%[3]s = t.id GROUP BY kind
+					) k
+				),
+				'{}'
+			) AS counts
+			FROM %[1]s t
+		)
+		UPDATE %[1]s t SET reaction_counts = actual.counts
+		FROM actual
+		WHERE t.id = actual.id AND t.reaction_counts <> actual.counts
+		RETURNING t.id
+	`, target.table, target.reactions, target.column)
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
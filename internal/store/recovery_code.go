@@ -0,0 +1,66 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/lib/pq"
+	"github.com/sangtandoan/social/internal/utils"
+)
+
+type recoveryCodeStore struct {
+	db *sql.DB
+}
+
+func NewRecoveryCodeStore(db *sql.DB) *recoveryCodeStore {
+	return &recoveryCodeStore{db}
+}
+
+// ReplaceAll drops every previous code of the user, codes must already be hashed
+func (s *recoveryCodeStore) ReplaceAll(ctx context.Context, userID int64, codes []string) error {
+	executor := GetExecutor(ctx, s.db)
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
+	defer cancel()
+
+	_, err := executor.ExecContext(ctx, "DELETE FROM mfa_recovery_codes WHERE user_id = $1", userID)
+	if err != nil {
+		return err
+	}
+
+	query := "INSERT INTO mfa_recovery_codes (user_id, code) SELECT $1, unnest($2::bytea[])"
+
+	_, err = executor.ExecContext(ctx, query, userID, pq.Array(codes))
+	return err
+}
+
+func (s *recoveryCodeStore) Consume(ctx context.Context, userID int64, code string) error {
+	executor := GetExecutor(ctx, s.db)
+	query := "UPDATE mfa_recovery_codes SET used_at = NOW() WHERE user_id = $1 AND code = $2 AND used_at IS NULL"
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
+	defer cancel()
+
+	result, err := executor.ExecContext(ctx, query, userID, code)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return utils.ErrInvalidMFACode
+	}
+
+	return nil
+}
+
+func (s *recoveryCodeStore) DeleteByUserID(ctx context.Context, userID int64) error {
+	executor := GetExecutor(ctx, s.db)
+
+	_, err := executor.ExecContext(ctx, "DELETE FROM mfa_recovery_codes WHERE user_id = $1", userID)
+	return err
+}
@@ -0,0 +1,261 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/sangtandoan/social/internal/models/params"
+	"github.com/sangtandoan/social/internal/utils"
+)
+
+type refreshTokenStore struct {
+	db *sql.DB
+}
+
+func NewRefreshTokenStore(db *sql.DB) *refreshTokenStore {
+	return &refreshTokenStore{db}
+}
+
+// Why a token was revoked. Only a rotated token presented again was stolen, a
+// signed out one is merely stale.
+const (
+	RevokedRotated   = "rotated"
+	RevokedReused    = "reused"
+	RevokedSignedOut = "signed_out"
+)
+
+// Every rotation creates a new row in the same family, so a family is one login session
+type RefreshToken struct {
+	ExpiresAt  time.Time  `json:"expires_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	FamilyID   string     `json:"family_id"`
+	IPAddress  string     `json:"ip_address"`
+	UserAgent  string     `json:"user_agent"`
+	// RevokedReason is one of the Revoked values, empty while the token is usable
+	RevokedReason string `json:"revoked_reason,omitempty"`
+	ID            int64  `json:"id"`
+	UserID        int64  `json:"user_id"`
+	IsRevoked     bool   `json:"is_revoked"`
+}
+
+// Token must already be hashed, the raw value is only ever returned to the client
+func (s *refreshTokenStore) Create(
+	ctx context.Context,
+	arg *params.CreateRefreshTokenParams,
+) (*RefreshToken, error) {
+	executor := GetExecutor(ctx, s.db)
+	query := `
+		INSERT INTO refresh_tokens (user_id, token, expires_at, family_id, ip_address, user_agent)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
+	defer cancel()
+
+	row := executor.QueryRowContext(
+		ctx,
+		query,
+		arg.UserID,
+		arg.Token,
+		arg.ExpiresAt,
+		arg.FamilyID,
+		arg.IPAddress,
+		arg.UserAgent,
+	)
+
+	token := RefreshToken{
+		UserID:    arg.UserID,
+		ExpiresAt: arg.ExpiresAt,
+		FamilyID:  arg.FamilyID,
+		IPAddress: arg.IPAddress,
+		UserAgent: arg.UserAgent,
+	}
+	err := row.Scan(&token.ID, &token.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// GetByToken locks the row so two concurrent refreshes of the same token are serialized
+func (s *refreshTokenStore) GetByToken(ctx context.Context, token string) (*RefreshToken, error) {
+	executor := GetExecutor(ctx, s.db)
+	query := `
+		SELECT id, user_id, family_id, expires_at, created_at, last_used_at, ip_address, user_agent, is_revoked,
+			COALESCE(revoked_reason, '')
+		FROM refresh_tokens
+		WHERE token = $1
+		FOR UPDATE
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
+	defer cancel()
+
+	row := executor.QueryRowContext(ctx, query, token)
+
+	var res RefreshToken
+	err := row.Scan(
+		&res.ID,
+		&res.UserID,
+		&res.FamilyID,
+		&res.ExpiresAt,
+		&res.CreatedAt,
+		&res.LastUsedAt,
+		&res.IPAddress,
+		&res.UserAgent,
+		&res.IsRevoked,
+		&res.RevokedReason,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+// Revoke marks a token as used, it can never be exchanged again
+func (s *refreshTokenStore) Revoke(ctx context.Context, id int64) error {
+	executor := GetExecutor(ctx, s.db)
+	query := `
+		UPDATE refresh_tokens SET is_revoked = true, revoked_reason = $2, last_used_at = NOW()
+		WHERE id = $1
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
+	defer cancel()
+
+	_, err := executor.ExecContext(ctx, query, id, RevokedRotated)
+	return err
+}
+
+// RevokeFamily ends a session whose token was reused
+func (s *refreshTokenStore) RevokeFamily(ctx context.Context, familyID string) error {
+	executor := GetExecutor(ctx, s.db)
+	query := `
+		UPDATE refresh_tokens SET is_revoked = true, revoked_reason = $2
+		WHERE family_id = $1 AND is_revoked = false
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
+	defer cancel()
+
+	_, err := executor.ExecContext(ctx, query, familyID, RevokedReused)
+	return err
+}
+
+// RecordReuse keeps track of who presented an already rotated token
+func (s *refreshTokenStore) RecordReuse(
+	ctx context.Context,
+	arg *params.RecordTokenReuseParams,
+) error {
+	executor := GetExecutor(ctx, s.db)
+	query := `
+		UPDATE refresh_tokens
+		SET reused_at = NOW(), reused_ip_address = $2, reused_user_agent = $3
+		WHERE id = $1
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
+	defer cancel()
+
+	_, err := executor.ExecContext(ctx, query, arg.ID, arg.IPAddress, arg.UserAgent)
+	return err
+}
+
+type Session struct {
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	ID         string    `json:"id"`
+	Device     string    `json:"device"`
+	IPAddress  string    `json:"ip_address"`
+	Current    bool      `json:"current"`
+}
+
+// ListSessions returns one entry per token family that still holds a usable token.
+// The live token of a family was issued on its last refresh, so its created_at is
+// the last time the session was used.
+func (s *refreshTokenStore) ListSessions(ctx context.Context, userID int64) ([]*Session, error) {
+	query := `
+		SELECT
+			rt.family_id, rt.user_agent, rt.ip_address, rt.created_at, rt.expires_at,
+			(SELECT MIN(f.created_at) FROM refresh_tokens f WHERE f.family_id = rt.family_id)
+		FROM refresh_tokens rt
+		WHERE rt.user_id = $1 AND rt.is_revoked = false AND rt.expires_at > NOW()
+		ORDER BY rt.created_at DESC
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := []*Session{}
+	for rows.Next() {
+		var session Session
+
+		err := rows.Scan(
+			&session.ID,
+			&session.Device,
+			&session.IPAddress,
+			&session.LastUsedAt,
+			&session.ExpiresAt,
+			&session.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		res = append(res, &session)
+	}
+
+	return res, rows.Err()
+}
+
+func (s *refreshTokenStore) RevokeSession(ctx context.Context, userID int64, familyID string) error {
+	query := `
+		UPDATE refresh_tokens SET is_revoked = true, revoked_reason = $3
+		WHERE user_id = $1 AND family_id = $2 AND is_revoked = false
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx, query, userID, familyID, RevokedSignedOut)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return utils.ErrNotFound
+	}
+
+	return nil
+}
+
+func (s *refreshTokenStore) RevokeAllUserTokens(ctx context.Context, userID int64) error {
+	executor := GetExecutor(ctx, s.db)
+	query := `
+		UPDATE refresh_tokens SET is_revoked = true, revoked_reason = $2
+		WHERE user_id = $1 AND is_revoked = false
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
+	defer cancel()
+
+	_, err := executor.ExecContext(ctx, query, userID, RevokedSignedOut)
+	return err
+}
@@ -0,0 +1,162 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/sangtandoan/social/internal/utils"
+)
+
+// Permission lets a role act on content or accounts it does not own
+type Permission string
+
+const (
+	PermPostUpdateAny   Permission = "post:update:any"
+	PermPostDeleteAny   Permission = "post:delete:any"
+	PermCommentModerate Permission = "comment:moderate"
+	PermUserSuspend     Permission = "user:suspend"
+	PermRoleAssign      Permission = "role:assign"
+	PermPolicyManage    Permission = "policy:manage"
+)
+
+// RoleUser is the implicit role of every member, it is stored as a NULL role_id
+const RoleUser = "user"
+
+// userHasPermission matches when the user's role grants the permission, for
+// statements that check it in the same query as the write
+func userHasPermission(userParam, permissionParam int) string {
+	return fmt.Sprintf(`EXISTS (
+		SELECT 1
+		FROM role_permissions rp
+		JOIN users u ON u.role_id = rp.role_id
+		WHERE u.id = $%d AND rp.permission = $%d
+	)`, userParam, permissionParam)
+}
+
+type roleStore struct {
+	db *sql.DB
+}
+
+func NewRoleStore(db *sql.DB) *roleStore {
+	return &roleStore{db}
+}
+
+func (s *roleStore) CheckPermission(
+	ctx context.Context,
+	userID int64,
+	permission Permission,
+) (bool, error) {
+	executor := GetExecutor(ctx, s.db)
+	query := `
+		SELECT EXISTS (
+			SELECT 1
+			FROM role_permissions rp
+			JOIN users u ON u.role_id = rp.role_id
+			WHERE u.id = $1 AND rp.permission = $2
+		)
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
+	defer cancel()
+
+	var exists bool
+	err := executor.QueryRowContext(ctx, query, userID, string(permission)).Scan(&exists)
+	if err != nil {
+		return false, err
+	}
+
+	return exists, nil
+}
+
+func (s *roleStore) GetUserPermissions(ctx context.Context, userID int64) ([]Permission, error) {
+	query := `
+		SELECT rp.permission
+		FROM role_permissions rp
+		JOIN users u ON u.role_id = rp.role_id
+		WHERE u.id = $1
+		ORDER BY rp.permission
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	permissions := []Permission{}
+	for rows.Next() {
+		var permission string
+		if err := rows.Scan(&permission); err != nil {
+			return nil, err
+		}
+		permissions = append(permissions, Permission(permission))
+	}
+
+	return permissions, rows.Err()
+}
+
+// GetUserRole returns the name of the user's role, RoleUser when they have none
+func (s *roleStore) GetUserRole(ctx context.Context, userID int64) (string, error) {
+	executor := GetExecutor(ctx, s.db)
+	query := `
+		SELECT COALESCE(r.name, $2)
+		FROM users u
+		LEFT JOIN roles r ON r.id = u.role_id
+		WHERE u.id = $1
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
+	defer cancel()
+
+	var role string
+	err := executor.QueryRowContext(ctx, query, userID, RoleUser).Scan(&role)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", utils.ErrNotFound
+		}
+		return "", err
+	}
+
+	return role, nil
+}
+
+// AssignRole gives the user a role by name, RoleUser removes any role
+func (s *roleStore) AssignRole(ctx context.Context, userID int64, role string) error {
+	executor := GetExecutor(ctx, s.db)
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
+	defer cancel()
+
+	var roleID sql.NullInt64
+	if role != RoleUser {
+		err := executor.QueryRowContext(ctx, "SELECT id FROM roles WHERE name = $1", role).
+			Scan(&roleID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return utils.ErrRoleNotFound
+			}
+			return err
+		}
+	}
+
+	result, err := executor.ExecContext(ctx, "UPDATE users SET role_id = $1 WHERE id = $2", roleID, userID)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return utils.ErrNotFound
+	}
+
+	return nil
+}
@@ -8,11 +8,14 @@ import (
 
 	"github.com/sangtandoan/social/internal/models/dto"
 	"github.com/sangtandoan/social/internal/models/params"
+	"github.com/sangtandoan/social/internal/utils"
 )
 
 var (
 	ErrNotFound  = errors.New("resource not found")
 	QueryTimeOut = time.Second * 5
+	// ErrNothingToUpdate is returned for a partial update that changes no field
+	ErrNothingToUpdate = errors.New("nothing to update")
 )
 
 type Executor interface {
@@ -31,9 +34,52 @@ type Store struct {
 	Posts interface {
 		Create(context.Context, *Post) error
 		GetByID(ctx context.Context, id int64) (*Post, error)
-		GetAll(ctx context.Context) ([]*Post, error)
+		GetAll(ctx context.Context, viewerID int64, page *dto.Pagination) ([]*Post, *utils.Cursor, error)
+		IsVisibleTo(ctx context.Context, postID, viewerID int64) (bool, error)
 		UpdatePost(ctx context.Context, arg *UpdatePostParams) (*Post, error)
-		GetUserFeed(ctx context.Context, arg *dto.UserFeedRequest) ([]*PostResponse, error)
+		Delete(ctx context.Context, id, userID int64) error
+		Restore(ctx context.Context, id, userID int64) error
+		PurgeDeleted(ctx context.Context, before time.Time, limit int) (int64, error)
+		PublishDue(ctx context.Context, limit int) ([]int64, error)
+		GetUserFeed(
+			ctx context.Context,
+			arg *dto.UserFeedRequest,
+		) ([]*PostResponse, *utils.Cursor, error)
+	}
+
+	PostRevisions interface {
+		LockForEdit(ctx context.Context, postID int64) error
+		Create(ctx context.Context, post *Post, editedBy int64) error
+		ListByPostID(ctx context.Context, postID int64) ([]*PostRevision, error)
+		Get(ctx context.Context, postID int64, revision int) (*PostRevision, error)
+	}
+
+	Comments interface {
+		Create(ctx context.Context, comment *Comment) error
+		Get(ctx context.Context, id, postID int64) (*Comment, error)
+		Update(ctx context.Context, id, postID, userID int64, content string) (*Comment, error)
+		Delete(ctx context.Context, id, postID, userID int64) error
+		ListByPostID(
+			ctx context.Context,
+			postID int64,
+			page *dto.Pagination,
+		) ([]*Comment, *utils.Cursor, error)
+		ListReplies(
+			ctx context.Context,
+			parentID int64,
+			page *dto.Pagination,
+		) ([]*Comment, *utils.Cursor, error)
+		AttachReplies(ctx context.Context, roots []*Comment, depth int) error
+	}
+
+	Reactions interface {
+		Toggle(
+			ctx context.Context,
+			target ReactionTarget,
+			targetID, userID int64,
+			kind string,
+		) (string, ReactionCounts, error)
+		Reconcile(ctx context.Context, target ReactionTarget) ([]int64, error)
 	}
 
 	Users interface {
@@ -42,16 +88,102 @@ type Store struct {
 		GetByEmail(ctx context.Context, email string) (*User, error)
 		Activate(ctx context.Context, id int64) error
 		Delete(ctx context.Context, id int64) error
+		UpdatePassword(ctx context.Context, id int64, password string) error
+		RecordFailedLogin(ctx context.Context, id int64) (*LoginAttempts, error)
+		Lock(ctx context.Context, id int64, until time.Time) error
+		ResetFailedLogins(ctx context.Context, id int64) error
+		SetPendingMFASecret(ctx context.Context, id int64, secret string) error
+		EnableMFA(ctx context.Context, id int64) error
+		DisableMFA(ctx context.Context, id int64) error
+		UseTOTPStep(ctx context.Context, id int64, step int64) (bool, error)
+		SetSuspended(ctx context.Context, id int64, suspended bool) error
+		SetPrivate(ctx context.Context, id int64, private bool) error
+	}
+
+	Roles interface {
+		CheckPermission(ctx context.Context, userID int64, permission Permission) (bool, error)
+		GetUserPermissions(ctx context.Context, userID int64) ([]Permission, error)
+		GetUserRole(ctx context.Context, userID int64) (string, error)
+		AssignRole(ctx context.Context, userID int64, role string) error
 	}
 
 	Followers interface {
-		Follow(ctx context.Context, arg *FollowParams) error
+		Follow(ctx context.Context, arg *FollowParams) (bool, error)
 		Unfollow(ctx context.Context, arg *UnfollowParams) error
+		IsFollowing(ctx context.Context, userID, followerID int64) (bool, error)
+		Counts(ctx context.Context, userID int64) (*FollowCounts, error)
+		ListFollowers(
+			ctx context.Context,
+			userID int64,
+			page *dto.Pagination,
+		) ([]*FollowUser, *utils.Cursor, error)
+		ListFollowing(
+			ctx context.Context,
+			userID int64,
+			page *dto.Pagination,
+		) ([]*FollowUser, *utils.Cursor, error)
+	}
+
+	FollowRequests interface {
+		ListIncoming(
+			ctx context.Context,
+			userID int64,
+			page *dto.Pagination,
+		) ([]*FollowRequest, *utils.Cursor, error)
+		Approve(ctx context.Context, userID, requesterID int64) error
+		ApproveAll(ctx context.Context, userID int64) error
+		Delete(ctx context.Context, userID, requesterID int64) error
+	}
+
+	Policies interface {
+		List(ctx context.Context) ([]*Policy, error)
+		ListEnabled(ctx context.Context) ([]*Policy, error)
+		Create(ctx context.Context, policy *Policy) error
+		Update(ctx context.Context, policy *Policy) error
+		Delete(ctx context.Context, id int64) error
 	}
 
 	Invitations interface {
 		CreateInvitation(ctx context.Context, arg *params.CreateInvitationParams) error
 		GetUserIDFromInvitation(ctx context.Context, token string) (int64, error)
+		MarkInvitationUsed(ctx context.Context, token string) error
+		DeleteUnusedByUserID(ctx context.Context, userID int64) error
+	}
+
+	PasswordResets interface {
+		CreatePasswordReset(ctx context.Context, arg *params.CreatePasswordResetParams) error
+		ConsumePasswordReset(ctx context.Context, token string) (int64, error)
+		DeleteByUserID(ctx context.Context, userID int64) error
+	}
+
+	APIKeys interface {
+		Create(ctx context.Context, arg *params.CreateAPIKeyParams) (*APIKey, error)
+		ListByUserID(ctx context.Context, userID int64) ([]*APIKey, error)
+		GetByHash(ctx context.Context, keyHash string) (*APIKey, error)
+		Touch(ctx context.Context, id int64) error
+		Delete(ctx context.Context, userID, id int64) error
+	}
+
+	Identities interface {
+		GetUserID(ctx context.Context, provider, subject string) (int64, error)
+		Create(ctx context.Context, arg *params.CreateIdentityParams) error
+	}
+
+	RecoveryCodes interface {
+		ReplaceAll(ctx context.Context, userID int64, codes []string) error
+		Consume(ctx context.Context, userID int64, code string) error
+		DeleteByUserID(ctx context.Context, userID int64) error
+	}
+
+	RefreshTokens interface {
+		Create(ctx context.Context, arg *params.CreateRefreshTokenParams) (*RefreshToken, error)
+		GetByToken(ctx context.Context, token string) (*RefreshToken, error)
+		Revoke(ctx context.Context, id int64) error
+		RevokeFamily(ctx context.Context, familyID string) error
+		RecordReuse(ctx context.Context, arg *params.RecordTokenReuseParams) error
+		ListSessions(ctx context.Context, userID int64) ([]*Session, error)
+		RevokeSession(ctx context.Context, userID int64, familyID string) error
+		RevokeAllUserTokens(ctx context.Context, userID int64) error
 	}
 
 	Tx Tx
@@ -59,11 +191,22 @@ type Store struct {
 
 func NewStore(db *sql.DB) *Store {
 	return &Store{
-		Posts:       &PostsStore{db},
-		Users:       &UsersStore{db},
-		Followers:   NewFollowerStore(db),
-		Invitations: NewInvitationStore(db),
-		Tx:          &tx{db},
+		Posts:          &PostsStore{db},
+		PostRevisions:  NewPostRevisionStore(db),
+		Comments:       NewCommentStore(db),
+		Reactions:      NewReactionStore(db),
+		Users:          &UsersStore{db},
+		Roles:          NewRoleStore(db),
+		Followers:      NewFollowerStore(db),
+		FollowRequests: NewFollowRequestStore(db),
+		Invitations:    NewInvitationStore(db),
+		PasswordResets: NewPasswordResetStore(db),
+		Policies:       NewPolicyStore(db),
+		APIKeys:        NewAPIKeyStore(db),
+		Identities:     NewIdentityStore(db),
+		RecoveryCodes:  NewRecoveryCodeStore(db),
+		RefreshTokens:  NewRefreshTokenStore(db),
+		Tx:             &tx{db},
 	}
 }
 
@@ -0,0 +1,76 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+)
+
+// testDB connects to the migrated database at TEST_DB_ADDR, the tests that need
+// one are skipped when it is not set
+func testDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	addr := os.Getenv("TEST_DB_ADDR")
+	if addr == "" {
+		t.Skip("TEST_DB_ADDR is not set")
+	}
+
+	db, err := sql.Open("postgres", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if err := db.Ping(); err != nil {
+		t.Fatal(err)
+	}
+
+	return db
+}
+
+// createTestUser adds a user with a unique name, removed with its posts after the test
+func createTestUser(t *testing.T, db *sql.DB) int64 {
+	t.Helper()
+
+	name := fmt.Sprintf("test_%d", time.Now().UnixNano())
+	query := "INSERT INTO users (username, email, password) VALUES ($1, $2, $3) RETURNING id"
+
+	var id int64
+	err := db.QueryRow(query, name, name+"@example.com", []byte("password")).Scan(&id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		db.Exec("DELETE FROM posts WHERE user_id = $1", id)
+		db.Exec("DELETE FROM users WHERE id = $1", id)
+	})
+
+	return id
+}
+
+// createTestPost adds a published public post of the user
+func createTestPost(t *testing.T, db *sql.DB, userID int64, title string, tags ...string) *Post {
+	t.Helper()
+
+	now := time.Now()
+	post := &Post{
+		Title:      title,
+		Content:    title,
+		Tags:       tags,
+		Status:     PostStatusPublished,
+		PublishAt:  &now,
+		Visibility: PostVisibilityPublic,
+		UserID:     int(userID),
+	}
+
+	if err := (&PostsStore{db}).Create(context.Background(), post); err != nil {
+		t.Fatal(err)
+	}
+
+	return post
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/sangtandoan/social/internal/models/dto"
+	"github.com/sangtandoan/social/internal/utils"
 )
 
 type UsersStore struct {
@@ -13,11 +14,50 @@ type UsersStore struct {
 }
 
 type User struct {
-	CreatedAt time.Time `json:"created_at"`
-	Username  string    `json:"username,omitempty"`
-	Email     string    `json:"email,omitempty"`
-	Password  string    `json:"password,omitempty"`
-	ID        int64     `json:"id,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	Username    string     `json:"username,omitempty"`
+	Email       string     `json:"email,omitempty"`
+	Password    string     `json:"password,omitempty"`
+	LockedUntil *time.Time `json:"-"`
+	SuspendedAt *time.Time `json:"-"`
+	MFASecret   string     `json:"-"`
+	ID          int64      `json:"id,omitempty"`
+	Active      bool       `json:"active"`
+	MFAEnabled  bool       `json:"mfa_enabled"`
+	// IsPrivate accounts approve their followers
+	IsPrivate bool `json:"is_private"`
+}
+
+const userColumns = `
+	id, username, email, password, created_at, COALESCE(active, false), locked_until,
+	COALESCE(mfa_secret, ''), mfa_enabled, suspended_at, is_private
+`
+
+func scanUser(row *sql.Row) (*User, error) {
+	var user User
+	err := row.Scan(
+		&user.ID,
+		&user.Username,
+		&user.Email,
+		&user.Password,
+		&user.CreatedAt,
+		&user.Active,
+		&user.LockedUntil,
+		&user.MFASecret,
+		&user.MFAEnabled,
+		&user.SuspendedAt,
+		&user.IsPrivate,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+type LoginAttempts struct {
+	Failed    int
+	LockCount int
 }
 
 func (s *UsersStore) Create(ctx context.Context, arg *dto.CreateUserRequest) (*User, error) {
@@ -40,38 +80,26 @@ func (s *UsersStore) Create(ctx context.Context, arg *dto.CreateUserRequest) (*U
 
 func (s *UsersStore) GetByID(ctx context.Context, id int64) (*User, error) {
 	executor := GetExecutor(ctx, s.db)
-	query := "SELECT id, username, email, password, created_at FROM users WHERE id = $1"
+	query := "SELECT " + userColumns + " FROM users WHERE id = $1"
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
 	defer cancel()
 
 	row := executor.QueryRowContext(ctx, query, id)
 
-	var user User
-	err := row.Scan(&user.ID, &user.Username, &user.Email, &user.Password, &user.CreatedAt)
-	if err != nil {
-		return nil, err
-	}
-
-	return &user, nil
+	return scanUser(row)
 }
 
 func (s *UsersStore) GetByEmail(ctx context.Context, email string) (*User, error) {
 	executor := GetExecutor(ctx, s.db)
-	query := "SELECT id, username, email, password, created_at FROM users WHERE email = $1"
+	query := "SELECT " + userColumns + " FROM users WHERE email = $1"
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
 	defer cancel()
 
 	row := executor.QueryRowContext(ctx, query, email)
 
-	var user User
-	err := row.Scan(&user.ID, &user.Username, &user.Email, &user.Password, &user.CreatedAt)
-	if err != nil {
-		return nil, err
-	}
-
-	return &user, nil
+	return scanUser(row)
 }
 
 func (s *UsersStore) Delete(ctx context.Context, id int64) error {
@@ -91,3 +119,167 @@ func (s *UsersStore) Activate(ctx context.Context, id int64) error {
 	_, err := executor.ExecContext(ctx, query, id)
 	return err
 }
+
+func (s *UsersStore) UpdatePassword(ctx context.Context, id int64, password string) error {
+	executor := GetExecutor(ctx, s.db)
+	query := "UPDATE users SET password = $1 WHERE id = $2"
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
+	defer cancel()
+
+	_, err := executor.ExecContext(ctx, query, password, id)
+	return err
+}
+
+// RecordFailedLogin returns how many wrong passwords were given since the last success or lock
+func (s *UsersStore) RecordFailedLogin(ctx context.Context, id int64) (*LoginAttempts, error) {
+	executor := GetExecutor(ctx, s.db)
+	query := `
+		UPDATE users SET failed_login_attempts = failed_login_attempts + 1
+		WHERE id = $1
+		RETURNING failed_login_attempts, lock_count
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
+	defer cancel()
+
+	var attempts LoginAttempts
+	err := executor.QueryRowContext(ctx, query, id).Scan(&attempts.Failed, &attempts.LockCount)
+	if err != nil {
+		return nil, err
+	}
+
+	return &attempts, nil
+}
+
+func (s *UsersStore) Lock(ctx context.Context, id int64, until time.Time) error {
+	executor := GetExecutor(ctx, s.db)
+	query := `
+		UPDATE users
+		SET locked_until = $1, lock_count = lock_count + 1, failed_login_attempts = 0
+		WHERE id = $2
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
+	defer cancel()
+
+	_, err := executor.ExecContext(ctx, query, until, id)
+	return err
+}
+
+func (s *UsersStore) ResetFailedLogins(ctx context.Context, id int64) error {
+	executor := GetExecutor(ctx, s.db)
+	query := `
+		UPDATE users
+		SET failed_login_attempts = 0, lock_count = 0, locked_until = NULL
+		WHERE id = $1
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
+	defer cancel()
+
+	_, err := executor.ExecContext(ctx, query, id)
+	return err
+}
+
+// SetPendingMFASecret stores a secret that only becomes active after EnableMFA
+func (s *UsersStore) SetPendingMFASecret(ctx context.Context, id int64, secret string) error {
+	executor := GetExecutor(ctx, s.db)
+	query := "UPDATE users SET mfa_secret = $1, mfa_last_step = NULL WHERE id = $2 AND mfa_enabled = false"
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
+	defer cancel()
+
+	_, err := executor.ExecContext(ctx, query, secret, id)
+	return err
+}
+
+func (s *UsersStore) EnableMFA(ctx context.Context, id int64) error {
+	executor := GetExecutor(ctx, s.db)
+	query := "UPDATE users SET mfa_enabled = true WHERE id = $1 AND mfa_secret IS NOT NULL"
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
+	defer cancel()
+
+	_, err := executor.ExecContext(ctx, query, id)
+	return err
+}
+
+func (s *UsersStore) DisableMFA(ctx context.Context, id int64) error {
+	executor := GetExecutor(ctx, s.db)
+	query := "UPDATE users SET mfa_enabled = false, mfa_secret = NULL, mfa_last_step = NULL WHERE id = $1"
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
+	defer cancel()
+
+	_, err := executor.ExecContext(ctx, query, id)
+	return err
+}
+
+// UseTOTPStep returns false when a code of the same or a later time step was already accepted
+func (s *UsersStore) UseTOTPStep(ctx context.Context, id int64, step int64) (bool, error) {
+	executor := GetExecutor(ctx, s.db)
+	query := `
+		UPDATE users SET mfa_last_step = $1
+		WHERE id = $2 AND (mfa_last_step IS NULL OR mfa_last_step < $1)
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
+	defer cancel()
+
+	result, err := executor.ExecContext(ctx, query, step, id)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected == 1, nil
+}
+
+// SetSuspended suspends the user when suspended is true, otherwise lifts the suspension
+func (s *UsersStore) SetSuspended(ctx context.Context, id int64, suspended bool) error {
+	executor := GetExecutor(ctx, s.db)
+	query := `
+		UPDATE users
+		SET suspended_at = CASE WHEN $1 THEN COALESCE(suspended_at, NOW()) ELSE NULL END
+		WHERE id = $2
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
+	defer cancel()
+
+	result, err := executor.ExecContext(ctx, query, suspended, id)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return utils.ErrNotFound
+	}
+
+	return nil
+}
+
+func (s *UsersStore) SetPrivate(ctx context.Context, id int64, private bool) error {
+	executor := GetExecutor(ctx, s.db)
+	query := "UPDATE users SET is_private = $1 WHERE id = $2"
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeOut)
+	defer cancel()
+
+	result, err := executor.ExecContext(ctx, query, private, id)
+	if err != nil {
+		return err
+	}
+
+	return checkAffected(result)
+}
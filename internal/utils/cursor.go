@@ -0,0 +1,60 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var ErrInvalidCursor = NewApiError(http.StatusBadRequest, "invalid cursor")
+
+// Cursor is the position of the last item of a page ordered by (created_at, id) descending.
+// Post listings order by publish_at instead, CreatedAt then holds the publish time,
+// and replies go oldest first.
+type Cursor struct {
+	CreatedAt time.Time `json:"t"`
+	ID        int64     `json:"i"`
+}
+
+// EncodeCursor returns an opaque token for the cursor, signed so clients can not
+// forge positions
+func EncodeCursor(secret []byte, cursor Cursor) string {
+	payload, _ := json.Marshal(cursor)
+
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(signCursor(secret, encoded))
+}
+
+func DecodeCursor(secret []byte, token string) (*Cursor, error) {
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, ErrInvalidCursor
+	}
+
+	mac, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(mac, signCursor(secret, encoded)) {
+		return nil, ErrInvalidCursor
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var cursor Cursor
+	if err := json.Unmarshal(payload, &cursor); err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	return &cursor, nil
+}
+
+func signCursor(secret []byte, encoded string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(encoded))
+	return mac.Sum(nil)
+}
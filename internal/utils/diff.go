@@ -0,0 +1,144 @@
+package utils
+
+import (
+	"slices"
+	"strings"
+)
+
+type DiffOp string
+
+const (
+	DiffEqual  DiffOp = "equal"
+	DiffInsert DiffOp = "insert"
+	DiffDelete DiffOp = "delete"
+)
+
+type DiffLine struct {
+	Op   DiffOp `json:"op"`
+	Text string `json:"text"`
+}
+
+// maxDiffEdits bounds the work of DiffLines, the memory it takes grows with the
+// square of the edit distance
+const maxDiffEdits = 1000
+
+// DiffLines returns the shortest line-level edit script from a to b,
+// computed with the Myers algorithm. Texts further apart than maxDiffEdits lines
+// get a coarser script, which replaces everything between their common prefix
+// and suffix.
+func DiffLines(a, b string) []DiffLine {
+	x, y := splitLines(a), splitLines(b)
+	n, m := len(x), len(y)
+
+	maxD := min(n+m, maxDiffEdits)
+	offset := maxD + 1
+	v := make([]int, 2*maxD+3)
+
+	// trace[d] holds the furthest reaching paths before step d, for the diagonals
+	// -d-1 to d+1 that step d reads
+	var trace [][]int
+	for d := 0; d <= maxD; d++ {
+		trace = append(trace, slices.Clone(v[offset-d-1:offset+d+2]))
+
+		for k := -d; k <= d; k += 2 {
+			var i int
+			if k == -d || k != d && v[offset+k-1] < v[offset+k+1] {
+				i = v[offset+k+1]
+			} else {
+				i = v[offset+k-1] + 1
+			}
+
+			j := i - k
+			for i < n && j < m && x[i] == y[j] {
+				i++
+				j++
+			}
+			v[offset+k] = i
+
+			if i >= n && j >= m {
+				return backtrackDiff(trace, x, y)
+			}
+		}
+	}
+
+	return replaceDiff(x, y)
+}
+
+func backtrackDiff(trace [][]int, x, y []string) []DiffLine {
+	var res []DiffLine
+
+	i, j := len(x), len(y)
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		// Diagonal k of step d is at v[k+offset]
+		offset := d + 1
+		k := i - j
+
+		var prevK int
+		if k == -d || k != d && v[offset+k-1] < v[offset+k+1] {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+
+		prevI := v[offset+prevK]
+		prevJ := prevI - prevK
+
+		for i > prevI && j > prevJ {
+			res = append(res, DiffLine{Op: DiffEqual, Text: x[i-1]})
+			i--
+			j--
+		}
+
+		if d > 0 {
+			if i == prevI {
+				res = append(res, DiffLine{Op: DiffInsert, Text: y[j-1]})
+			} else {
+				res = append(res, DiffLine{Op: DiffDelete, Text: x[i-1]})
+			}
+		}
+
+		i, j = prevI, prevJ
+	}
+
+	slices.Reverse(res)
+	return res
+}
+
+// replaceDiff keeps the common prefix and suffix of x and y and replaces the rest
+func replaceDiff(x, y []string) []DiffLine {
+	prefix := 0
+	for prefix < len(x) && prefix < len(y) && x[prefix] == y[prefix] {
+		prefix++
+	}
+
+	suffix := 0
+	for suffix < len(x)-prefix && suffix < len(y)-prefix &&
+		x[len(x)-1-suffix] == y[len(y)-1-suffix] {
+		suffix++
+	}
+
+	res := make([]DiffLine, 0, len(x)+len(y)-prefix-suffix)
+	for _, line := range x[:prefix] {
+		res = append(res, DiffLine{Op: DiffEqual, Text: line})
+	}
+	for _, line := range x[prefix : len(x)-suffix] {
+		res = append(res, DiffLine{Op: DiffDelete, Text: line})
+	}
+	for _, line := range y[prefix : len(y)-suffix] {
+		res = append(res, DiffLine{Op: DiffInsert, Text: line})
+	}
+	for _, line := range x[len(x)-suffix:] {
+		res = append(res, DiffLine{Op: DiffEqual, Text: line})
+	}
+
+	return res
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+
+	return strings.Split(s, "\n")
+}
@@ -0,0 +1,126 @@
+package utils
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestDiffLines(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want []DiffLine
+	}{
+		{"both empty", "", "", nil},
+		{
+			"identical",
+			"a\nb",
+			"a\nb",
+			[]DiffLine{{DiffEqual, "a"}, {DiffEqual, "b"}},
+		},
+		{
+			"from empty",
+			"",
+			"a\nb",
+			[]DiffLine{{DiffInsert, "a"}, {DiffInsert, "b"}},
+		},
+		{
+			"to empty",
+			"a\nb",
+			"",
+			[]DiffLine{{DiffDelete, "a"}, {DiffDelete, "b"}},
+		},
+		{
+			"insert in the middle",
+			"a\nc",
+			"a\nb\nc",
+			[]DiffLine{{DiffEqual, "a"}, {DiffInsert, "b"}, {DiffEqual, "c"}},
+		},
+		{
+			"replace a line",
+			"a\nb\nc",
+			"a\nx\nc",
+			[]DiffLine{{DiffEqual, "a"}, {DiffDelete, "b"}, {DiffInsert, "x"}, {DiffEqual, "c"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DiffLines(tt.a, tt.b)
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("DiffLines(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffLinesIsShortest(t *testing.T) {
+	tests := []struct {
+		a, b  string
+		edits int
+	}{
+		{"a\nb\nc\na\nb\nb\na", "c\nb\na\nb\na\nc", 5},
+		{"a\nb\nc\nd", "d\nc\nb\na", 6},
+		{"x\na\ny\nb\nz", "a\nb", 3},
+	}
+
+	for _, tt := range tests {
+		got := DiffLines(tt.a, tt.b)
+		checkDiff(t, tt.a, tt.b, got)
+
+		if edits := countEdits(got); edits != tt.edits {
+			t.Errorf("DiffLines(%q, %q) has %d edits, want %d", tt.a, tt.b, edits, tt.edits)
+		}
+	}
+}
+
+func TestDiffLinesBoundsEdits(t *testing.T) {
+	var a, b []string
+	for i := range maxDiffEdits {
+		a = append(a, fmt.Sprintf("old %d", i))
+		b = append(b, fmt.Sprintf("new %d", i))
+	}
+	a = append([]string{"head"}, append(a, "tail")...)
+	b = append([]string{"head"}, append(b, "tail")...)
+
+	got := DiffLines(strings.Join(a, "\n"), strings.Join(b, "\n"))
+	checkDiff(t, strings.Join(a, "\n"), strings.Join(b, "\n"), got)
+
+	if got[0] != (DiffLine{DiffEqual, "head"}) || got[len(got)-1] != (DiffLine{DiffEqual, "tail"}) {
+		t.Fatal("the common prefix and suffix were not kept")
+	}
+	if edits := countEdits(got); edits != 2*maxDiffEdits {
+		t.Fatalf("%d edits, want %d", edits, 2*maxDiffEdits)
+	}
+}
+
+// checkDiff verifies the script turns a into b
+func checkDiff(t *testing.T, a, b string, diff []DiffLine) {
+	t.Helper()
+
+	var from, to []string
+	for _, line := range diff {
+		if line.Op != DiffInsert {
+			from = append(from, line.Text)
+		}
+		if line.Op != DiffDelete {
+			to = append(to, line.Text)
+		}
+	}
+
+	if !slices.Equal(from, splitLines(a)) || !slices.Equal(to, splitLines(b)) {
+		t.Fatalf("diff %v does not turn %q into %q", diff, a, b)
+	}
+}
+
+func countEdits(diff []DiffLine) int {
+	edits := 0
+	for _, line := range diff {
+		if line.Op != DiffEqual {
+			edits++
+		}
+	}
+	return edits
+}
@@ -6,9 +6,47 @@ import (
 )
 
 var (
-	ErrInvalidJSON  = NewApiError(http.StatusBadRequest, "invalid json format")
-	ErrNotFound     = NewApiError(http.StatusNotFound, "resource not found")
-	ErrUnauthorized = NewApiError(http.StatusUnauthorized, "unauthorized")
+	ErrInvalidJSON       = NewApiError(http.StatusBadRequest, "invalid json format")
+	ErrNotFound          = NewApiError(http.StatusNotFound, "resource not found")
+	ErrUnauthorized      = NewApiError(http.StatusUnauthorized, "unauthorized")
+	ErrInvalidToken      = NewApiError(http.StatusUnauthorized, "invalid or expired token")
+	ErrInsufficientScope = NewApiError(http.StatusForbidden, "the credential is not allowed to do this")
+	ErrTooManyRequests   = NewApiError(http.StatusTooManyRequests, "too many requests")
+	ErrForbidden         = NewApiError(http.StatusForbidden, "you do not have permission to do this")
+	ErrRoleNotFound      = NewApiError(http.StatusBadRequest, "role does not exist")
+
+	ErrPreconditionFailed = NewApiError(
+		http.StatusPreconditionFailed,
+		"the resource has been changed since you fetched it",
+	)
+
+	ErrAccountInactive  = NewApiError(http.StatusForbidden, "account has not been activated")
+	ErrAccountSuspended = NewApiError(http.StatusForbidden, "account has been suspended")
+	ErrAccountLocked    = NewApiError(
+		http.StatusLocked,
+		"account is temporarily locked after too many failed login attempts",
+	)
+
+	ErrInvalidMFACode    = NewApiError(http.StatusUnauthorized, "invalid authentication code")
+	ErrMFAAlreadyEnabled = NewApiError(http.StatusConflict, "two-factor authentication is already enabled")
+	ErrMFANotEnabled     = NewApiError(http.StatusBadRequest, "two-factor authentication is not enabled")
+	ErrMFANotEnrolled    = NewApiError(http.StatusBadRequest, "start the two-factor enrollment first")
+
+	ErrOAuthInvalidState     = NewApiError(http.StatusBadRequest, "invalid or expired oauth state")
+	ErrOAuthFailed           = NewApiError(http.StatusUnauthorized, "can not verify the identity with the provider")
+	ErrOAuthEmailNotVerified = NewApiError(http.StatusForbidden, "the provider has not verified this email")
+
+	ErrInvitationNotFound = NewApiError(http.StatusNotFound, "activate token not found")
+	ErrInvitationExpired  = NewApiError(http.StatusGone, "activate token has expired")
+	ErrInvitationUsed     = NewApiError(http.StatusConflict, "activate token has already been used")
+
+	ErrTokenReused = NewApiError(
+		http.StatusUnauthorized,
+		"refresh token has already been used, please login again",
+	)
+
+	ErrParentCommentNotFound = NewApiError(http.StatusBadRequest, "parent comment not found")
+	ErrAlreadyFollowing      = NewApiError(http.StatusConflict, "you are already following this user")
 )
 
 type ApiError struct {
@@ -0,0 +1,20 @@
+package utils
+
+import (
+	"regexp"
+	"slices"
+)
+
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w@])@(\w{3,50})\b`)
+
+// ExtractMentions returns the distinct usernames @mentioned in text
+func ExtractMentions(text string) []string {
+	var usernames []string
+	for _, match := range mentionPattern.FindAllStringSubmatch(text, -1) {
+		if !slices.Contains(usernames, match[1]) {
+			usernames = append(usernames, match[1])
+		}
+	}
+
+	return usernames
+}
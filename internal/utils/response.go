@@ -1,11 +1,17 @@
 package utils
 
 type apiReponse struct {
-	Data    any    `json:"data,omitempty"`
-	Msg     string `json:"msg,omitempty"`
-	Success bool   `json:"success,omitempty"`
+	Data any    `json:"data,omitempty"`
+	Msg  string `json:"msg,omitempty"`
+	// NextCursor fetches the following page, it is empty on the last one
+	NextCursor string `json:"next_cursor,omitempty"`
+	Success    bool   `json:"success,omitempty"`
 }
 
 func NewApiResponse(msg string, data any) *apiReponse {
 	return &apiReponse{Msg: msg, Data: data}
 }
+
+func NewPageResponse(msg string, data any, nextCursor string) *apiReponse {
+	return &apiReponse{Msg: msg, Data: data, NextCursor: nextCursor}
+}
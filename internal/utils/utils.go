@@ -1,6 +1,8 @@
 package utils
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"net/http"
@@ -40,3 +42,9 @@ func MakeHandlerFunc(f ApiFunc) gin.HandlerFunc {
 		}
 	}
 }
+
+// Tokens are stored as sha256 hex digests so a leaked table can not be replayed
+func HashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}